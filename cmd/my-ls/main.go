@@ -1,41 +1,14 @@
 package main
 
 import (
-	"fmt"
-	"log"
-	internal "my-ls/internal/ls"
+	internal "github.com/DavJesse/ls-clone/internal/ls"
 	"os"
 )
 
 func main() {
 	args := os.Args[1:] // Retrieve arguments from command line
 
-	// Extract flags and paths from user arguments
-	// Handle errors, if encountered
-	flag, path, err := internal.SortArgs(args)
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	files := internal.RetrieveFileInfo(path, false)
-	for i := range files {
-		fmt.Print("Index: ")
-		fmt.Println(files[i].Index)
-
-		fmt.Print("File/Dir Name: ")
-		fmt.Println(files[i].DocName)
-
-		fmt.Print("File/Dir Detail: ")
-		fmt.Println(files[i].DocPerm)
-
-		fmt.Println("Recursive List:")
-		if len(files[i].RecursiveList) > 0 {
-			internal.UnravelFiles(files[i].RecursiveList)
-		}
-		//fmt.Println(files[i].RecursiveList)
-		fmt.Println()
-		fmt.Println()
-	}
-	//fmt.Println(files)
-	fmt.Println(flag)
+	// The command itself lives in internal.Run, so it can also be run in-process
+	// Run reports every error on stderr itself, so nothing here logs or exits early
+	os.Exit(internal.Run(args, os.Getenv, os.Stdout, os.Stderr))
 }
@@ -1,3 +1,3 @@
-module my-ls
+module github.com/DavJesse/ls-clone
 
 go 1.22.5
@@ -0,0 +1,432 @@
+// This file lists the members of tar and zip archives as if they were directories.
+// An archive is indexed once, reading only its headers, into an in-memory filesystem
+// that plugs into the same listing, sorting and display code as the real one.
+
+package internal
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Name endings of archives listed as directories
+var ArchiveExtensions = []string{".tar", ".tar.gz", ".tgz", ".zip"}
+
+// An archive named on the command line, and the directory to list inside it
+type ArchiveOperand struct {
+	Spec string // The operand as given, e.g. 'release.tar.gz:/bin'
+	File string // The archive file
+	Dir  string // io/fs name of the member to list, "." for the top
+}
+
+// Reports whether a file name looks like an archive
+func IsArchiveName(name string) bool {
+	lower := strings.ToLower(name)
+	for _, ext := range ArchiveExtensions {
+		if strings.HasSuffix(lower, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// Splits 'FILE[:DIR]' into the archive file and the member to list
+// A ':' only separates the two when what comes before it names an archive,
+// so ordinary names holding ':' are left alone
+func ParseArchiveSpec(spec string) ArchiveOperand {
+	if i := strings.LastIndex(spec, ":"); i > 0 && IsArchiveName(spec[:i]) {
+		return splitArchiveSpec(spec, i)
+	}
+	return ArchiveOperand{Spec: spec, File: spec, Dir: "."}
+}
+
+// Reads the value of --archive=FILE[:DIR]
+// Any archive file may be named this way, whatever its name ends with
+func ParseArchiveOption(spec string) ArchiveOperand {
+	if _, err := os.Stat(spec); err != nil {
+		if i := strings.LastIndex(spec, ":"); i > 0 {
+			return splitArchiveSpec(spec, i)
+		}
+	}
+	return ArchiveOperand{Spec: spec, File: spec, Dir: "."}
+}
+
+// Splits a spec at the ':' at index 'i'; the member is cleaned into an io/fs name
+func splitArchiveSpec(spec string, i int) ArchiveOperand {
+	operand := ArchiveOperand{Spec: spec, File: spec[:i], Dir: "."}
+	if dir := strings.Trim(path.Clean("/"+spec[i+1:]), "/"); dir != "" {
+		operand.Dir = dir
+	}
+	return operand
+}
+
+// Picks out operands to be listed as archives: archive files, and 'FILE:DIR' paths into them
+// With -d, archive files are listed as themselves, like any other file
+func SplitArchives(paths []string, opts Options) ([]string, []ArchiveOperand) {
+	var plain []string
+	var archives []ArchiveOperand
+
+	for _, path := range paths {
+		if opts.Directory || opts.FS != nil {
+			plain = append(plain, path)
+			continue
+		}
+
+		// A real file named like 'a.zip:b' is listed as itself
+		if info, err := os.Stat(path); err == nil {
+			if info.Mode().IsRegular() && IsArchiveName(path) {
+				archives = append(archives, ArchiveOperand{Spec: path, File: path, Dir: "."})
+			} else {
+				plain = append(plain, path)
+			}
+			continue
+		}
+
+		if operand := ParseArchiveSpec(path); operand.File != path {
+			archives = append(archives, operand)
+		} else {
+			plain = append(plain, path)
+		}
+	}
+	return plain, archives
+}
+
+// An archive's members, indexed by their io/fs names
+// Member contents are not kept, so only metadata can be read
+type ArchiveFS struct {
+	nodes map[string]*archiveNode
+}
+
+type archiveNode struct {
+	name     string
+	meta     MetaData
+	children []string // Names of members directly inside a directory
+}
+
+// Indexes a tar, gzip-compressed tar or zip archive
+// The format is told from the file's contents rather than its name
+func OpenArchive(file string) (*ArchiveFS, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	archive := &ArchiveFS{nodes: map[string]*archiveNode{}}
+	archive.nodes["."] = &archiveNode{name: ".", meta: archiveDirMeta(info.ModTime())}
+
+	reader := bufio.NewReader(f)
+	magic, _ := reader.Peek(4)
+
+	switch {
+	case len(magic) >= 4 && string(magic) == "PK\x03\x04", len(magic) >= 4 && string(magic) == "PK\x05\x06":
+		err = archive.indexZip(f, info.Size())
+	case len(magic) >= 2 && magic[0] == 0x1f && magic[1] == 0x8b:
+		var gz *gzip.Reader
+		if gz, err = gzip.NewReader(reader); err == nil {
+			err = archive.indexTar(tar.NewReader(gz), info.ModTime())
+		}
+	default:
+		err = archive.indexTar(tar.NewReader(reader), info.ModTime())
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, node := range archive.nodes {
+		sort.Strings(node.children)
+	}
+	return archive, nil
+}
+
+// Adds every member of a tar stream, skipping over member contents
+func (a *ArchiveFS) indexTar(reader *tar.Reader, modTime time.Time) error {
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		meta := MetaData{
+			HardLinkCount: 1,
+			UserID:        header.Uname,
+			GroupID:       header.Gname,
+			UID:           uint32(header.Uid),
+			GID:           uint32(header.Gid),
+			Mode:          header.FileInfo().Mode(),
+			Size:          header.Size,
+			AccessTime:    header.AccessTime,
+			ModTime:       header.ModTime,
+			ChangeTime:    header.ChangeTime,
+		}
+		if meta.UserID == "" {
+			meta.UserID = strconv.Itoa(header.Uid)
+		}
+		if meta.GroupID == "" {
+			meta.GroupID = strconv.Itoa(header.Gid)
+		}
+
+		switch header.Typeflag {
+		case tar.TypeSymlink:
+			meta.LinkTarget = header.Linkname
+			meta.Size = int64(len(header.Linkname))
+		case tar.TypeChar, tar.TypeBlock:
+			meta.Rdev = MakeDevice(uint64(header.Devmajor), uint64(header.Devminor))
+		case tar.TypeDir:
+			meta.HardLinkCount = 2
+		}
+		a.add(header.Name, meta, modTime)
+	}
+}
+
+// Adds every member listed in a zip's central directory
+// Zip archives record no owners, so they show as '?'
+func (a *ArchiveFS) indexZip(file io.ReaderAt, size int64) error {
+	reader, err := zip.NewReader(file, size)
+	if err != nil {
+		return err
+	}
+
+	for _, member := range reader.File {
+		meta := MetaData{
+			HardLinkCount: 1,
+			UserID:        "?",
+			GroupID:       "?",
+			Mode:          member.Mode(),
+			Size:          int64(member.UncompressedSize64),
+			AccessTime:    member.Modified,
+			ModTime:       member.Modified,
+			ChangeTime:    member.Modified,
+		}
+		if meta.Mode.IsDir() {
+			meta.HardLinkCount = 2
+		}
+
+		// Zip keeps a link's target as its contents, the only contents ever read
+		if meta.Mode&fs.ModeSymlink != 0 {
+			if meta.LinkTarget, err = readZipLink(member); err != nil {
+				return err
+			}
+		}
+		a.add(member.Name, meta, member.Modified)
+	}
+	return nil
+}
+
+// Reads where a zip member that is a symbolic link points
+func readZipLink(member *zip.File) (string, error) {
+	reader, err := member.Open()
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close()
+
+	target, err := io.ReadAll(io.LimitReader(reader, 4096))
+	return string(target), err
+}
+
+// Records a member under its cleaned name, creating any parent directories the archive left out
+// Names can't escape the archive: '/x' and '../x' are both listed as 'x'
+func (a *ArchiveFS) add(name string, meta MetaData, modTime time.Time) {
+	name = strings.Trim(path.Clean("/"+name), "/")
+	if name == "" || !fs.ValidPath(name) {
+		return
+	}
+	meta.Blocks = (meta.Size + 511) / 512
+
+	if node, ok := a.nodes[name]; ok {
+		// A directory first seen as a parent gets its real metadata
+		node.meta = meta
+		return
+	}
+	a.nodes[name] = &archiveNode{name: name, meta: meta}
+
+	for {
+		parent := path.Dir(name)
+		node, ok := a.nodes[parent]
+		if !ok {
+			node = &archiveNode{name: parent, meta: archiveDirMeta(modTime)}
+			a.nodes[parent] = node
+		}
+		node.children = append(node.children, path.Base(name))
+		if ok {
+			return
+		}
+		name = parent
+	}
+}
+
+// Metadata for directories an archive implies but does not hold
+func archiveDirMeta(modTime time.Time) MetaData {
+	return MetaData{
+		HardLinkCount: 2,
+		UserID:        "?",
+		GroupID:       "?",
+		Mode:          fs.ModeDir | 0o755,
+		AccessTime:    modTime,
+		ModTime:       modTime,
+		ChangeTime:    modTime,
+	}
+}
+
+func (a *ArchiveFS) lookup(op, name string) (*archiveNode, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	node, ok := a.nodes[name]
+	if !ok {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+	return node, nil
+}
+
+func (a *ArchiveFS) Open(name string) (fs.File, error) {
+	node, err := a.lookup("open", name)
+	if err != nil {
+		return nil, err
+	}
+	return &archiveFile{fsys: a, node: node}, nil
+}
+
+// Links inside archives are not followed, so Stat describes members as Lstat does
+func (a *ArchiveFS) Stat(name string) (fs.FileInfo, error) {
+	node, err := a.lookup("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	return archiveInfo{node}, nil
+}
+
+func (a *ArchiveFS) Lstat(name string) (MetaData, error) {
+	node, err := a.lookup("lstat", name)
+	if err != nil {
+		return MetaData{}, err
+	}
+	return node.meta, nil
+}
+
+// An open archive member; directories can be read, file contents cannot
+type archiveFile struct {
+	fsys   *ArchiveFS
+	node   *archiveNode
+	offset int
+}
+
+func (f *archiveFile) Stat() (fs.FileInfo, error) {
+	return archiveInfo{f.node}, nil
+}
+
+func (f *archiveFile) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: f.node.name, Err: errors.ErrUnsupported}
+}
+
+func (f *archiveFile) Close() error {
+	return nil
+}
+
+func (f *archiveFile) ReadDir(n int) ([]fs.DirEntry, error) {
+	if !f.node.meta.Mode.IsDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: f.node.name, Err: syscall.ENOTDIR}
+	}
+
+	names := f.node.children[f.offset:]
+	if n > 0 && len(names) > n {
+		names = names[:n]
+	}
+	if n > 0 && len(names) == 0 {
+		return nil, io.EOF
+	}
+	f.offset += len(names)
+
+	entries := make([]fs.DirEntry, 0, len(names))
+	for _, name := range names {
+		child := f.fsys.nodes[path.Join(f.node.name, name)]
+		entries = append(entries, fs.FileInfoToDirEntry(archiveInfo{child}))
+	}
+	return entries, nil
+}
+
+// Describes an archive member through fs.FileInfo
+type archiveInfo struct {
+	node *archiveNode
+}
+
+func (i archiveInfo) Name() string       { return path.Base(i.node.name) }
+func (i archiveInfo) Size() int64        { return i.node.meta.Size }
+func (i archiveInfo) Mode() fs.FileMode  { return i.node.meta.Mode }
+func (i archiveInfo) ModTime() time.Time { return i.node.meta.ModTime }
+func (i archiveInfo) IsDir() bool        { return i.node.meta.Mode.IsDir() }
+func (i archiveInfo) Sys() any           { return nil }
+
+// Lists the member an archive operand names
+// A directory member returns its contents, true; any other member, or any member with -d,
+// returns a single entry named as the operand was given, false
+func RetrieveArchive(operand ArchiveOperand, opts Options) ([]FileInfo, bool, error) {
+	fsys, err := OpenArchive(operand.File)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) || errors.Is(err, fs.ErrPermission) {
+			return nil, false, &OperandError{Op: "access", Path: operand.File, Err: err}
+		}
+		return nil, false, &OperandError{Op: "read archive", Path: operand.File, Err: err}
+	}
+	opts.FS = fsys
+
+	meta, err := fsys.Lstat(operand.Dir)
+	if err != nil {
+		return nil, false, &OperandError{Op: "access", Path: operand.Spec, Err: err}
+	}
+
+	if !meta.Mode.IsDir() || opts.Directory {
+		doc, err := ReadOperand(fsys, operand.Dir)
+		if err != nil {
+			return nil, false, &OperandError{Op: "access", Path: operand.Spec, Err: err}
+		}
+		doc.Name = operand.Spec
+		doc.Path = operand.Spec
+		doc.Dir = operand.File
+		return []FileInfo{doc}, false, nil
+	}
+
+	// Members are read from memory, so only a damaged index fails here
+	files, errs := RetrieveEntries(operand.Dir, opts)
+	if len(errs) > 0 {
+		return nil, false, errors.Join(errs...)
+	}
+	PrefixArchivePaths(files, operand.File)
+	return files, true, nil
+}
+
+// Shows archive paths as 'FILE:/member', so -R headers and JSON paths name the archive
+func PrefixArchivePaths(files []FileInfo, file string) {
+	for i := range files {
+		files[i].Path = archivePath(file, files[i].Path)
+		files[i].Dir = archivePath(file, files[i].Dir)
+		PrefixArchivePaths(files[i].RecursiveList, file)
+	}
+}
+
+func archivePath(file, name string) string {
+	if name == "." {
+		return file + ":/"
+	}
+	return file + ":/" + name
+}
@@ -0,0 +1,82 @@
+// This file lets listings be read from any io/fs filesystem, not only the operating system's.
+// Traversal, sorting and formatting work the same on every backend; how much metadata
+// is shown depends on what the backend can describe.
+
+package internal
+
+import (
+	"io/fs"
+	"os"
+	"path"
+)
+
+// An fs.FS that can describe entries as fully as ls -l shows them: owner, links, inode and link targets
+// Backends without it are listed from what fs.FileInfo offers: mode, size and modification time
+type MetaFS interface {
+	fs.FS
+
+	// Describes 'name' itself, rather than the file a symbolic link points to
+	Lstat(name string) (MetaData, error)
+}
+
+// The operating system's filesystem, used when Options.FS is nil
+// Unlike os.DirFS, paths are used as given, so absolute and '..' paths work as they do in ls
+type OSFS struct{}
+
+func (OSFS) Open(name string) (fs.File, error) {
+	return os.Open(name)
+}
+
+func (OSFS) Stat(name string) (fs.FileInfo, error) {
+	return os.Stat(name)
+}
+
+func (OSFS) Lstat(name string) (MetaData, error) {
+	return RetrieveMetaData(name)
+}
+
+// The filesystem a listing is read from
+func (opts Options) Filesystem() fs.FS {
+	if opts.FS == nil {
+		return OSFS{}
+	}
+	return opts.FS
+}
+
+// Describes 'name' without following a final symbolic link, as fully as the backend allows
+func StatEntry(fsys fs.FS, name string) (MetaData, error) {
+	if metaFS, ok := fsys.(MetaFS); ok {
+		return metaFS.Lstat(name)
+	}
+
+	info, err := fs.Stat(fsys, name)
+	if err != nil {
+		return MetaData{}, err
+	}
+	return MetaFromFileInfo(info), nil
+}
+
+// Fills what fs.FileInfo can tell about a file
+// Owner and group are unknown, so they show as '?', as ls shows fields it can't read
+func MetaFromFileInfo(info fs.FileInfo) MetaData {
+	return MetaData{
+		HardLinkCount: 1,
+		UserID:        "?",
+		GroupID:       "?",
+		Mode:          info.Mode(),
+		Size:          info.Size(),
+		Blocks:        (info.Size() + 511) / 512,
+		AccessTime:    info.ModTime(),
+		ModTime:       info.ModTime(),
+		ChangeTime:    info.ModTime(),
+	}
+}
+
+// Joins a directory and an entry name the way the backend names files
+// io/fs paths are unrooted and never hold '.' elements, so 'dir' "." joins to just 'name'
+func (opts Options) JoinPath(dir, name string) string {
+	if opts.FS == nil {
+		return JoinPath(dir, name)
+	}
+	return path.Join(dir, name)
+}
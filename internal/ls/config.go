@@ -0,0 +1,134 @@
+// This file loads default options from the user's config file and the MY_LS_OPTIONS
+// environment variable. Both are turned into arguments placed ahead of the command line,
+// so where options conflict, the command line wins over MY_LS_OPTIONS, which wins over the file.
+
+package internal
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Where the config file is read from, following the XDG base directory spec:
+// $XDG_CONFIG_HOME/my-ls/config, or ~/.config/my-ls/config when it is unset
+// Returns "" when neither variable gives an absolute directory
+func ConfigPath(env func(string) string) string {
+	base := env("XDG_CONFIG_HOME")
+	if !filepath.IsAbs(base) {
+		home := env("HOME")
+		if !filepath.IsAbs(home) {
+			return ""
+		}
+		base = filepath.Join(home, ".config")
+	}
+	return filepath.Join(base, "my-ls", "config")
+}
+
+// Collects the default arguments: the config file's, then those of MY_LS_OPTIONS
+// A missing config file is not an error; a bad option in either names where it was found
+func DefaultArgs(env func(string) string) ([]string, error) {
+	var defaults []string
+
+	if path := ConfigPath(env); path != "" {
+		file, err := os.Open(path)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+		if err == nil {
+			args, err := ParseConfig(file, path)
+			file.Close()
+			if err != nil {
+				return nil, err
+			}
+			defaults = append(defaults, args...)
+		}
+	}
+
+	// Split on spaces, as an unquoted shell variable would be
+	args := strings.Fields(env("MY_LS_OPTIONS"))
+	if err := checkDefaults(args); err != nil {
+		return nil, fmt.Errorf("MY_LS_OPTIONS: %w", err)
+	}
+	return append(defaults, args...), nil
+}
+
+// Turns a config file into long options, one per line:
+//
+//	# Comments and blank lines are skipped
+//	almost-all
+//	sort = time
+//	hide = "*.o"
+//	recursive = false
+//
+// Keys are long option names. A value of true, or none, switches an option on and false
+// leaves it off; options taking an argument take the value, with any quotes removed
+// 'name' labels errors, which point at the line they were found on
+func ParseConfig(r io.Reader, name string) ([]string, error) {
+	var args []string
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		key, value, hasValue := strings.Cut(text, "=")
+		key, value = strings.TrimSpace(key), unquoteConfigValue(strings.TrimSpace(value))
+
+		arg := "--" + key
+		if matches := LookupLongOption(key); len(matches) == 1 && matches[0].Arg == "" && hasValue {
+			switch value {
+			case "true":
+			case "false":
+				continue
+			default:
+				return nil, fmt.Errorf("%s:%d: invalid value '%s' for '%s'\nexpected true or false", name, line, value, key)
+			}
+		} else if hasValue {
+			arg += "=" + value
+		}
+
+		if err := checkDefaults([]string{arg}); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", name, line, err)
+		}
+		args = append(args, arg)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return args, nil
+}
+
+// Removes the quotes around a value, or a trailing ' # comment' from an unquoted one
+func unquoteConfigValue(value string) string {
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') {
+		if end := strings.IndexByte(value[1:], value[0]); end >= 0 {
+			return value[1 : end+1]
+		}
+	}
+	if comment := strings.Index(value, " #"); comment >= 0 {
+		value = strings.TrimSpace(value[:comment])
+	}
+	return value
+}
+
+// Checks default arguments hold valid options only, as files given there would be
+// listed on every run, and flags given after them on the command line rejected
+// Any path is refused, '.' included, since the command line's own paths would follow it
+func checkDefaults(args []string) error {
+	_, paths, err := parseArgs(args)
+	if err != nil {
+		return err
+	}
+	if len(paths) > 0 {
+		return errors.New("only options may be given, not '" + paths[0] + "'")
+	}
+	return nil
+}
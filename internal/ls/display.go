@@ -3,14 +3,452 @@
 // handling file permissions, user, group, size, modification time, etc.
 package internal
 
-import "fmt"
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
 
-func UnravelFiles(files []FileInfo) {
+func UnravelFiles(w io.Writer, files []FileInfo, opts Options) error {
 	//var relPath string
+	inodeWidth := InodeWidth(files)
 	for i := range files {
-		fmt.Println(files[i].DocName)
+		if _, err := fmt.Fprintln(w, AlignedEntryName(files[i], opts, inodeWidth)); err != nil {
+			return err
+		}
 		if len(files[i].RecursiveList) > 0 {
-			UnravelFiles(files[i].RecursiveList)
+			if err := UnravelFiles(w, files[i].RecursiveList, opts); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Formats an entry's name for short listings
+// With -i, the inode number leads the name, as ls does
+func EntryName(file FileInfo, opts Options) string {
+	return AlignedEntryName(file, opts, 0)
+}
+
+// Same as EntryName, with the inode right-aligned to 'inodeWidth' digits,
+// so that names line up within a listing
+func AlignedEntryName(file FileInfo, opts Options, inodeWidth int) string {
+	if opts.Inode {
+		return fmt.Sprintf("%*d %v", inodeWidth, file.Meta.Inode, FormatName(file, opts))
+	}
+	return FormatName(file, opts)
+}
+
+// Gives the number of digits of the largest inode among entries
+// With -i, ls pads every inode of a listing to it, short or long
+func InodeWidth(files []FileInfo) int {
+	var width int
+	for i := range files {
+		width = max(width, len(strconv.FormatUint(files[i].Meta.Inode, 10)))
+	}
+	return width
+}
+
+// Formats an entry's detail line for long listings (-l)
+// With -i, the inode number leads the line, as ls does
+func EntryDetail(file FileInfo, opts Options) string {
+	if opts.Inode {
+		return fmt.Sprintf("%d %v", file.Meta.Inode, FormatDetail(file, opts))
+	}
+	return FormatDetail(file, opts)
+}
+
+// Quotes an entry's name and decorates it with its color and indicator
+// Directories are bright blue with a trailing '/'
+// Symbolic links are bright cyan with a trailing '@'
+// Executable files are bright green with a trailing '*'
+func FormatName(file FileInfo, opts Options) string {
+	name := QuoteName(file.Name, opts.QuotingStyle, opts.HideControl)
+	mode := file.Meta.Mode
+
+	if color := TypeColor(mode); color != "" && !opts.NoColor {
+		name = fmt.Sprintf("\033[%vm%v\033[0m", color, name)
+	}
+	return name + TypeIndicator(mode)
+}
+
+// Gives the color ls uses for a type of file, empty for regular files
+func TypeColor(mode os.FileMode) string {
+	switch {
+	case mode.IsDir():
+		return "01;34"
+	case mode&os.ModeSymlink != 0:
+		return "01;36"
+	case mode&os.ModeNamedPipe != 0:
+		return "40;33"
+	case mode&os.ModeSocket != 0:
+		return "01;35"
+	case mode&os.ModeDevice != 0:
+		return "40;33;01"
+	case mode&0o111 != 0:
+		return "01;32"
+	}
+	return ""
+}
+
+// Gives the character that follows a name to show its type, as ls -F does
+func TypeIndicator(mode os.FileMode) string {
+	switch {
+	case mode.IsDir():
+		return "/"
+	case mode&os.ModeSymlink != 0:
+		return "@"
+	case mode&os.ModeNamedPipe != 0:
+		return "|"
+	case mode&os.ModeSocket != 0:
+		return "="
+	case mode&os.ModeDevice != 0:
+		return ""
+	case mode&0o111 != 0:
+		return "*"
+	}
+	return ""
+}
+
+// Formats an entry's permissions, links, owner, group, size, time and name
+// Fields are separated by single spaces; RenderLong aligns them into columns
+func FormatDetail(file FileInfo, opts Options) string {
+	return strings.Join(LongFields(file, opts, time.Now()), " ")
+}
+
+// Splits an entry's -l row into its columns: mode, links, owner, group, size, time and name
+// Symbolic links show their target after the name
+func LongFields(file FileInfo, opts Options, now time.Time) []string {
+	meta := file.Meta
+
+	// Devices show their major and minor numbers in place of a size
+	size := strconv.FormatInt(meta.Size, 10)
+	if opts.HumanReadable {
+		size = HumanSize(meta.Size)
+	}
+	if meta.Mode&os.ModeDevice != 0 {
+		size = fmt.Sprintf("%d, %d", DeviceMajor(meta.Rdev), DeviceMinor(meta.Rdev))
+	}
+
+	// Symbolic links show their target, which carries the indicator in place of the link
+	// The target is looked up in the listing's own filesystem; backends that can't
+	// read links leave it unknown, and the link is shown as itself
+	name := FormatName(file, opts)
+	if meta.Mode&os.ModeSymlink != 0 && meta.LinkTarget != "" {
+		name = strings.TrimSuffix(name, "@") + " -> " + QuoteName(meta.LinkTarget, opts.QuotingStyle, opts.HideControl)
+		if target, err := fs.Stat(opts.Filesystem(), file.Path); err == nil {
+			name += TypeIndicator(target.Mode())
 		}
 	}
+
+	return []string{
+		SymbolicMode(meta.Mode),
+		strconv.Itoa(meta.HardLinkCount),
+		meta.UserID,
+		meta.GroupID,
+		size,
+		FormatTime(meta.ModTime, now),
+		name,
+	}
+}
+
+// Formats a timestamp as ls -l does
+// Times within the past six months show the time of day, older or future ones the year
+func FormatTime(t, now time.Time) string {
+	sixMonths := now.Add(-time.Duration(31556952/2) * time.Second)
+	if t.After(sixMonths) && !t.After(now) {
+		return t.Format("Jan _2 15:04")
+	}
+	return t.Format("Jan _2  2006")
+}
+
+// Formats a size as -h does: in bytes below 1024, otherwise in K, M, G, T, P or E,
+// rounded up, with one decimal below 10, as ls does
+func HumanSize(size int64) string {
+	if size < 1024 {
+		return strconv.FormatInt(size, 10)
+	}
+
+	value := float64(size)
+	for _, unit := range "KMGTPE" {
+		value /= 1024
+		if tenths := math.Ceil(value * 10); tenths < 100 {
+			return fmt.Sprintf("%.1f%c", tenths/10, unit)
+		}
+		// Rounding up to 1024 carries over into the next unit
+		if whole := math.Ceil(value); whole < 1024 || unit == 'E' {
+			return fmt.Sprintf("%.0f%c", whole, unit)
+		}
+	}
+	return ""
+}
+
+// Major number of a device, as encoded by Linux
+func DeviceMajor(rdev uint64) uint64 {
+	return (rdev>>8)&0xfff | (rdev>>32)&^0xfff
+}
+
+// Minor number of a device, as encoded by Linux
+func DeviceMinor(rdev uint64) uint64 {
+	return rdev&0xff | (rdev>>12)&^0xff
+}
+
+// Encodes a device number from its major and minor numbers, as Linux does
+func MakeDevice(major, minor uint64) uint64 {
+	return (major&0xfff)<<8 | (major&^0xfff)<<32 | minor&0xff | (minor&^0xff)<<12
+}
+
+// Sums the disk usage of entries in 1024-byte blocks, as the -l 'total' line shows
+func TotalBlocks(files []FileInfo) int64 {
+	var total int64
+	for i := range files {
+		total += files[i].Meta.Blocks
+	}
+	return (total + 1) / 2
+}
+
+// Writes entries one per line, or as aligned rows with -l
+func RenderEntries(w io.Writer, files []FileInfo, opts Options) error {
+	if opts.Long {
+		return RenderLong(w, files, opts)
+	}
+
+	inodeWidth := InodeWidth(files)
+	for i := range files {
+		if _, err := fmt.Fprintln(w, AlignedEntryName(files[i], opts, inodeWidth)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Writes entries as -l rows, padding each column to its widest value
+// Numbers are right-aligned, owner and group left-aligned, as ls does
+func RenderLong(w io.Writer, files []FileInfo, opts Options) error {
+	now := time.Now()
+	rows := make([][]string, len(files))
+	widths := make([]int, 7)
+	inodeWidth := InodeWidth(files)
+
+	for i := range files {
+		rows[i] = LongFields(files[i], opts, now)
+		for j := 0; j < 6; j++ {
+			widths[j] = max(widths[j], len(rows[i][j]))
+		}
+	}
+
+	for i, row := range rows {
+		line := fmt.Sprintf("%-*s %*s %-*s %-*s %*s %s %s",
+			widths[0], row[0], widths[1], row[1], widths[2], row[2],
+			widths[3], row[3], widths[4], row[4], row[5], row[6])
+		if opts.Inode {
+			line = fmt.Sprintf("%*d %v", inodeWidth, files[i].Meta.Inode, line)
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Writes a directory's listing, headed by 'path:' when header is set
+// With -l, a 'total' line of disk usage comes first
+// With -R, sections for each subdirectory follow, always headed
+func RenderDirectory(w io.Writer, path string, files []FileInfo, opts Options, header bool) error {
+	if header {
+		if _, err := fmt.Fprintf(w, "%v:\n", QuoteName(path, opts.QuotingStyle, opts.HideControl)); err != nil {
+			return err
+		}
+	}
+	if opts.Long {
+		total := strconv.FormatInt(TotalBlocks(files), 10)
+		if opts.HumanReadable {
+			total = HumanSize(TotalBlocks(files) * 1024)
+		}
+		if _, err := fmt.Fprintf(w, "total %v\n", total); err != nil {
+			return err
+		}
+	}
+	if err := RenderEntries(w, files, opts); err != nil {
+		return err
+	}
+	if err := FlushSection(w); err != nil {
+		return err
+	}
+
+	if !opts.Recursive {
+		return nil
+	}
+	for i := range files {
+		if !IsDescended(files[i]) {
+			continue
+		}
+		// Directories that couldn't be read were reported instead, and get no section, as in ls
+		if files[i].RecursiveList == nil {
+			continue
+		}
+		if _, err := fmt.Fprintln(w); err != nil {
+			return err
+		}
+		if err := RenderDirectory(w, files[i].Path, files[i].RecursiveList, opts, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Reports whether a directory can be printed while it is read
+// Only unsorted short listings qualify: -l and -i need every entry to align their columns,
+// and -R lists subdirectories after their parent
+func CanStream(opts Options) bool {
+	return opts.Unsorted && !opts.Long && !opts.Inode && !opts.Recursive && !opts.HardLinks
+}
+
+// Writes a directory's entries as they are read, headed by 'path:' when header is set
+// Memory use stays constant, however many entries the directory holds
+// A directory that can't be read is returned as an *OperandError, once what was read is written
+func StreamDirectoryListing(w io.Writer, path string, opts Options, header bool) error {
+	if header {
+		if _, err := fmt.Fprintf(w, "%v:\n", QuoteName(path, opts.QuotingStyle, opts.HideControl)); err != nil {
+			return err
+		}
+	}
+
+	var writeErr error
+	err := StreamDirectory(path, opts, func(file FileInfo) error {
+		_, writeErr = fmt.Fprintln(w, EntryName(file, opts))
+		return writeErr
+	})
+	if writeErr != nil {
+		return writeErr
+	}
+	if flushErr := FlushSection(w); flushErr != nil {
+		return flushErr
+	}
+	if err != nil {
+		return &OperandError{Op: "open directory", Path: path, Err: err}
+	}
+	return nil
+}
+
+// Writes out a finished directory section if 'w' buffers its output, as a bufio.Writer does
+// Flushing once per section keeps writes large while each section still appears whole
+func FlushSection(w io.Writer) error {
+	if flusher, ok := w.(interface{ Flush() error }); ok {
+		return flusher.Flush()
+	}
+	return nil
+}
+
+// Reports whether a file is a terminal, as stdout is when not redirected
+func IsTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// Writes every group of entries that are hard links to the same file
+func PrintHardLinks(w io.Writer, groups [][]FileInfo) error {
+	for _, group := range groups {
+		if _, err := fmt.Fprintf(w, "inode %d:", group[0].Meta.Inode); err != nil {
+			return err
+		}
+		for i := range group {
+			if _, err := fmt.Fprintf(w, " %v", group[i].Path); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintln(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Formats a file mode the way ls -l does, e.g. 'drwxr-xr-x'
+// Setuid, setgid and sticky bits replace the matching execute slot
+func SymbolicMode(mode os.FileMode) string {
+	result := []byte("----------")
+
+	switch {
+	case mode&os.ModeDir != 0:
+		result[0] = 'd'
+	case mode&os.ModeSymlink != 0:
+		result[0] = 'l'
+	case mode&os.ModeNamedPipe != 0:
+		result[0] = 'p'
+	case mode&os.ModeSocket != 0:
+		result[0] = 's'
+	case mode&os.ModeCharDevice != 0:
+		result[0] = 'c'
+	case mode&os.ModeDevice != 0:
+		result[0] = 'b'
+	}
+
+	const rwx = "rwxrwxrwx"
+	for i := 0; i < 9; i++ {
+		if mode&(1<<uint(8-i)) != 0 {
+			result[i+1] = rwx[i]
+		}
+	}
+
+	// Special bits show as lowercase when the execute bit is set, uppercase otherwise
+	special := []struct {
+		bit   os.FileMode
+		index int
+		char  byte
+	}{
+		{os.ModeSetuid, 3, 's'},
+		{os.ModeSetgid, 6, 's'},
+		{os.ModeSticky, 9, 't'},
+	}
+	for _, sp := range special {
+		if mode&sp.bit != 0 {
+			if result[sp.index] == 'x' {
+				result[sp.index] = sp.char
+			} else {
+				result[sp.index] = sp.char - 'a' + 'A'
+			}
+		}
+	}
+	return string(result)
+}
+
+// Formats a file mode as unix octal permission bits, e.g. '0755'
+func OctalMode(mode os.FileMode) string {
+	bits := uint32(mode.Perm())
+	if mode&os.ModeSetuid != 0 {
+		bits |= 0o4000
+	}
+	if mode&os.ModeSetgid != 0 {
+		bits |= 0o2000
+	}
+	if mode&os.ModeSticky != 0 {
+		bits |= 0o1000
+	}
+	return fmt.Sprintf("%04o", bits)
+}
+
+// Names the type of file a mode describes
+func FileType(mode os.FileMode) string {
+	switch {
+	case mode&os.ModeDir != 0:
+		return "directory"
+	case mode&os.ModeSymlink != 0:
+		return "symlink"
+	case mode&os.ModeNamedPipe != 0:
+		return "fifo"
+	case mode&os.ModeSocket != 0:
+		return "socket"
+	case mode&os.ModeCharDevice != 0:
+		return "char_device"
+	case mode&os.ModeDevice != 0:
+		return "block_device"
+	}
+	return "file"
 }
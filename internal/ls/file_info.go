@@ -4,93 +4,356 @@
 package internal
 
 import (
+	"context"
 	"errors"
-	"fmt"
-	"log"
+	"io"
+	"io/fs"
 	"os"
 	"os/user"
-	"sort"
+	"path"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 	"syscall"
 )
 
-func RetrieveFileInfo(path string, includeHidden bool) []FileInfo {
-	var ResultList []FileInfo
-	var doc FileInfo
-	var fileMetaData MetaData
-	var linkCount int
-	var userID, groupID string
+// Lists a directory and all its subdirectories,
+// including dotfiles (but not '.' and '..') when includeHidden is set
+// Entries come with their display strings (DocName, DocPerm) filled in
+// Directories that can't be read are listed without their contents
+func RetrieveFileInfo(path string, includeHidden bool) []LegacyFileInfo {
+	files, _ := RetrieveEntries(path, Options{AlmostAll: includeHidden, Recursive: true})
+	return DecorateEntries(files)
+}
 
-	// Open directory/file for reading
-	file, err := os.Open(path)
+// Formats the display strings of a listing and its subdirectories into legacy entries
+// Listings keep entries compact without them; renderers format from Meta instead
+func DecorateEntries(files []FileInfo) []LegacyFileInfo {
+	result := make([]LegacyFileInfo, len(files))
+	for i := range files {
+		result[i] = LegacyFileInfo{
+			Index:         strings.ToLower(files[i].Name),
+			DocName:       FormatName(files[i], Options{}),
+			DocPerm:       FormatDetail(files[i], Options{}),
+			RecursiveList: DecorateEntries(files[i].RecursiveList),
+			ModTime:       files[i].Meta.ModTime.String(),
+			Entry:         files[i],
+		}
+	}
+	return result
+}
+
+// Lists a directory as the options ask
+// With -R, subdirectories are listed into each entry's RecursiveList,
+// reading up to --jobs directories at once
+// Each directory that can't be read is reported, as 'cannot open directory', and the rest
+// still listed; when 'path' itself can't be, no entries are returned and the only error names it
+func RetrieveEntries(path string, opts Options) ([]FileInfo, []error) {
+	return RetrieveEntriesContext(context.Background(), path, opts)
+}
+
+// Same as RetrieveEntries, but -R stops reading subdirectories once ctx is cancelled
+func RetrieveEntriesContext(ctx context.Context, path string, opts Options) ([]FileInfo, []error) {
+	if opts.Recursive {
+		return NewScanner(opts).ScanContext(ctx, path)
+	}
+
+	files, err := ListDirectory(path, opts)
 	if err != nil {
-		log.Fatal(err)
+		return nil, []error{&OperandError{Op: "open directory", Path: path, Err: err}}
 	}
-	defer file.Close()
+	return files, nil
+}
 
-	entries, err := file.Readdir(-1)
+// Lists one directory, without descending into subdirectories
+// With -a, '.' and '..' lead the listing; with -A, only dotfiles are added
+// Entries are ordered by SortEntries, by name unless another sort is asked for
+func ListDirectory(path string, opts Options) ([]FileInfo, error) {
+	// Never nil once read, so an empty directory is told apart from one that couldn't be
+	ResultList := []FileInfo{}
+
+	// Open directory/file for reading
+	info, err := fs.Stat(opts.Filesystem(), path)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
-	// Retrieve directory/file name and append to fileList
-	// For directories, we add '/' or '\' depending on opperating system
-	for _, entry := range entries {
-		fileMetaData, err = RetrieveMetaData(path + "/" + entry.Name())
+	// A file operand is listed as itself, as ls does
+	if !info.IsDir() {
+		doc, err := ReadOperand(opts.Filesystem(), path)
 		if err != nil {
-			log.Fatal(err)
+			return nil, err
 		}
-		linkCount = fileMetaData.HardLinkCount
-		userID = fileMetaData.UserID
-		groupID = fileMetaData.GroupID
+		return []FileInfo{doc}, nil
+	}
+
+	err = StreamDirectory(path, opts, func(doc FileInfo) error {
+		// Append 'doc' to fileList
+		ResultList = append(ResultList, doc)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
 
-		if entry.IsDir() {
-			// ignore hidden directories
-			if IsHidden(entry.Name()) && !includeHidden {
+	// Sort files and directories lexicographically, or as the sort options ask
+	// Case sensitivity is NOT taken in cosideration, as ls does
+	SortEntries(ResultList, opts)
+
+	return ResultList, nil
+}
+
+// Entries read from a directory per batch when streaming
+const BatchSize = 1024
+
+// Reads a directory in batches, handing each entry to 'emit' as soon as its metadata is known
+// Entries arrive in the order the filesystem returns them, so memory use stays
+// constant however large the directory; with -a, '.' and '..' come first
+func StreamDirectory(path string, opts Options, emit func(FileInfo) error) error {
+	fsys := opts.Filesystem()
+	file, err := fsys.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	dir, ok := file.(fs.ReadDirFile)
+	if !ok {
+		return &fs.PathError{Op: "readdir", Path: path, Err: syscall.ENOTDIR}
+	}
+
+	// ReadDir never returns '.' and '..', so -a adds them from their own metadata
+	if opts.All {
+		for _, name := range []string{".", ".."} {
+			if IsEntryIgnored(name, opts) {
 				continue
 			}
 
-			doc.RecursiveList = RetrieveFileInfo(path+"/"+entry.Name(), includeHidden)
-			doc.Index = fmt.Sprintf("%v/", strings.ToLower(entry.Name()))
-			doc.DocName = fmt.Sprintf("\033[01;34m%v\033[0m/", entry.Name())
-			doc.ModTime = entry.ModTime().String()
-			doc.DocPerm = fmt.Sprintf("%v %d %v %v %d %s \033[01;34m%v\033[0m/", entry.Mode().Perm().String(), linkCount, userID, groupID, entry.Size(), entry.ModTime().Format("Jan 02 15:04"), entry.Name())
-
-			// Append 'doc' to fileList
-			ResultList = append(ResultList, doc)
-			doc = FileInfo{}
+			fileMetaData, err := StatEntry(fsys, DotEntryPath(path, name, opts))
+			if err != nil {
+				return err
+			}
+			if err := emit(NewEntry(path, name, fileMetaData)); err != nil {
+				return err
+			}
+		}
+	}
 
-			// Append result for file types
-		} else {
-			// ignore hidden files
-			if IsHidden(entry.Name()) && !includeHidden {
+	_, hasMeta := fsys.(MetaFS)
+	for {
+		// ReadDir only reads names and types, so skipped entries are never stat'ed
+		entries, err := dir.ReadDir(BatchSize)
+		for _, entry := range entries {
+			// ignore hidden files and directories before paying for their metadata
+			if IsEntryIgnored(entry.Name(), opts) || IsEntryHidden(path, entry.Name(), entry.IsDir(), opts) {
 				continue
 			}
 
-			// Add bright-green color to executable files
-			// Retain default color for non-executable
-			if IsExecutable(entry) {
-				doc.DocName = fmt.Sprintf("\033[01;32m%s\033[0m*", entry.Name())
-				doc.DocPerm = fmt.Sprintf("%v %d %v %v %d %s %v", entry.Mode().Perm().String(), linkCount, userID, groupID, entry.Size(), entry.ModTime().Format("Jan 02 15:04"), entry.Name())
-			} else {
-				doc.DocName = entry.Name()
-				doc.DocPerm = fmt.Sprintf("%v %d %v %v %d %s %v", entry.Mode().Perm().String(), linkCount, userID, groupID, entry.Size(), entry.ModTime().Format("Jan 02 15:04"), entry.Name())
+			fileMetaData, err := entryMetaData(fsys, hasMeta, opts.JoinPath(path, entry.Name()), entry)
+			if err != nil {
+				return err
+			}
+			doc := NewEntry(path, entry.Name(), fileMetaData)
+			doc.Path = opts.JoinPath(path, entry.Name())
+			if err := emit(doc); err != nil {
+				return err
 			}
-			doc.Index = fmt.Sprintf("%v", strings.ToLower(entry.Name()))
-			doc.ModTime = entry.ModTime().String()
+		}
 
-			// Append 'doc' to fileList
-			ResultList = append(ResultList, doc)
-			doc = FileInfo{}
+		if err == io.EOF || (err == nil && len(entries) == 0) {
+			return nil
+		}
+		if err != nil {
+			return err
 		}
 	}
+}
 
-	// Sort files and directories lexicographically
-	// Case sensitivity is NOT taken in cosideration, as ls does
-	sort.Sort(Alphabetic(ResultList))
+// Describes a directory entry, from the backend's metadata when it has any
+// Otherwise the entry's own fs.FileInfo saves a second lookup
+func entryMetaData(fsys fs.FS, hasMeta bool, name string, entry fs.DirEntry) (MetaData, error) {
+	if hasMeta {
+		return StatEntry(fsys, name)
+	}
+
+	info, err := entry.Info()
+	if err != nil {
+		return MetaData{}, err
+	}
+	return MetaFromFileInfo(info), nil
+}
+
+// Names the file behind '.' or '..' in directory 'dir'
+// io/fs paths can't climb above their root, so there '..' of the root is the root itself
+func DotEntryPath(dir, name string, opts Options) string {
+	if opts.FS == nil {
+		return JoinPath(dir, name)
+	}
+	if name == "." {
+		return dir
+	}
+	return path.Dir(dir)
+}
+
+// Builds the entry for 'name' in directory 'dir'
+func NewEntry(dir, name string, meta MetaData) FileInfo {
+	return FileInfo{Name: name, Path: JoinPath(dir, name), Dir: dir, Meta: meta}
+}
+
+// Sorts command-line operands into entries shown as themselves and directories listed by contents
+// With -d every operand is shown as itself
+// Symbolic links to directories are followed unless -l is given, as ls does
+// Operands that can't be accessed or opened are reported and left out, so the rest are still listed
+func SplitOperands(paths []string, opts Options) ([]FileInfo, []string, []error) {
+	var files []FileInfo
+	var dirEntries []FileInfo
+	var errs []error
+	fsys := opts.Filesystem()
+
+	for _, path := range paths {
+		meta, err := StatEntry(fsys, path)
+		if err != nil {
+			errs = append(errs, &OperandError{Op: "access", Path: path, Err: err})
+			continue
+		}
+
+		// Only what the sort options look at is kept for directories
+		meta = MetaData{Mode: meta.Mode, Size: meta.Size, ModTime: meta.ModTime}
+		if meta.Mode&os.ModeSymlink != 0 && !opts.Long {
+			if target, err := fs.Stat(fsys, path); err == nil && target.IsDir() {
+				meta = MetaData{Mode: target.Mode(), Size: target.Size(), ModTime: target.ModTime()}
+			}
+		}
+
+		if meta.Mode.IsDir() && !opts.Directory {
+			// Check the directory can be read before it is listed
+			dir, err := fsys.Open(path)
+			if err != nil {
+				errs = append(errs, &OperandError{Op: "open directory", Path: path, Err: err})
+				continue
+			}
+			dir.Close()
+
+			dirEntries = append(dirEntries, FileInfo{Name: path, Path: path, Meta: meta})
+			continue
+		}
+
+		doc, err := ReadOperand(fsys, path)
+		if err != nil {
+			errs = append(errs, &OperandError{Op: "access", Path: path, Err: err})
+			continue
+		}
+		files = append(files, doc)
+	}
+
+	// Operands are sorted like the entries of a directory
+	// Unsorted listings keep them in command-line order
+	SortEntries(files, opts)
+	SortEntries(dirEntries, opts)
+
+	var dirs []string
+	for i := range dirEntries {
+		dirs = append(dirs, dirEntries[i].Path)
+	}
+	return files, dirs, errs
+}
+
+// Builds an entry for a command-line operand itself, rather than its contents
+// The name is the path as given, as ls -d shows it
+func RetrieveOperand(path string) (FileInfo, error) {
+	return ReadOperand(OSFS{}, path)
+}
+
+// Same as RetrieveOperand, for a path in any filesystem
+func ReadOperand(fsys fs.FS, path string) (FileInfo, error) {
+	var doc FileInfo
+
+	fileMetaData, err := StatEntry(fsys, path)
+	if err != nil {
+		return doc, err
+	}
+
+	doc.Name = path
+	doc.Path = path
+	doc.Dir = filepath.Dir(path)
+	doc.Meta = fileMetaData
+	return doc, err
+}
+
+// Describes the system error behind a failed filesystem call, as strerror does
+// e.g. 'No such file or directory'
+func Strerror(err error) string {
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		err = pathErr.Err
+	}
 
-	return ResultList
+	// Backends other than the operating system report io/fs errors,
+	// which are described as the matching system errors
+	var errno syscall.Errno
+	if !errors.As(err, &errno) {
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			err = syscall.ENOENT
+		case errors.Is(err, fs.ErrPermission):
+			err = syscall.EACCES
+		}
+	}
+
+	message := err.Error()
+	if message == "" {
+		return message
+	}
+	return strings.ToUpper(message[:1]) + message[1:]
+}
+
+// Joins a directory and an entry name, without doubling a trailing '/'
+func JoinPath(dir, name string) string {
+	if strings.HasSuffix(dir, "/") {
+		return dir + name
+	}
+	return dir + "/" + name
+}
+
+// Decides whether an entry is left out of a listing by -I, --ignore or -B
+// These apply whatever -a and -A say
+func IsEntryIgnored(name string, opts Options) bool {
+	if opts.IgnoreBackups && strings.HasSuffix(name, "~") {
+		return true
+	}
+	return MatchesAny(opts.Ignore, name)
+}
+
+// Decides whether an entry is left out of a listing
+// -a and -A show every entry; otherwise --hide patterns and the options'
+// Hide function decide, falling back to hiding dotfiles
+func IsEntryHidden(dir, name string, isDir bool, opts Options) bool {
+	if opts.All || opts.AlmostAll {
+		return false
+	}
+	if MatchesAny(opts.HidePatterns, name) {
+		return true
+	}
+	if opts.Hide != nil {
+		return opts.Hide(dir, name, isDir)
+	}
+	return HideDotfiles(dir, name, isDir)
+}
+
+// Reports whether a name matches any of the glob patterns
+func MatchesAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// Hides dotfiles, as ls does by default
+func HideDotfiles(dir, name string, isDir bool) bool {
+	return IsHidden(name)
 }
 
 func RetrieveMetaData(path string) (MetaData, error) {
@@ -108,27 +371,122 @@ func RetrieveMetaData(path string) (MetaData, error) {
 		return result, err
 	}
 	result.HardLinkCount = int(stat.Nlink)
+	result.UID = stat.Uid
+	result.GID = stat.Gid
+	result.Inode = uint64(stat.Ino)
+	result.Device = uint64(stat.Dev)
+	result.Rdev = uint64(stat.Rdev)
+	result.Mode = info.Mode()
+	result.Size = info.Size()
+	result.Blocks = int64(stat.Blocks)
+	result.ModTime = info.ModTime()
+	result.AccessTime, result.ChangeTime = statTimes(stat)
+
+	// Record where symbolic links point
+	// A link that can't be read, as under /proc, is still listed, without its target, as ls does
+	if info.Mode()&os.ModeSymlink != 0 {
+		if target, err := os.Readlink(path); err == nil {
+			result.LinkTarget = target
+		}
+	}
 	groupID := strconv.Itoa(int(stat.Gid))
 	userID := strconv.Itoa(int(stat.Uid))
 
 	// Extract user
-	u, err1 := user.LookupId(userID)
+	u, err1 := lookupUser(userID)
 	if err1 != nil {
 		return result, err1
 	}
 
 	// Extract group
-	g, err2 := user.LookupGroupId(groupID)
+	g, err2 := lookupGroup(groupID)
 	if err2 != nil {
 		return result, err2
 	}
 
-	result.UserID = u.Username
-	result.GroupID = g.Name
+	result.UserID = u
+	result.GroupID = g
 
 	return result, err
 }
 
+// Names already looked up, shared by concurrent scans
+// Large trees hold few distinct owners, so each is resolved once
+var userNames, groupNames sync.Map
+
+// Resolves a user ID to its name, remembering the answer
+// IDs without a name are shown as numbers, as ls does
+func lookupUser(id string) (string, error) {
+	if name, ok := userNames.Load(id); ok {
+		return name.(string), nil
+	}
+	u, err := user.LookupId(id)
+	if errors.As(err, new(user.UnknownUserIdError)) {
+		userNames.Store(id, id)
+		return id, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	userNames.Store(id, u.Username)
+	return u.Username, nil
+}
+
+// Resolves a group ID to its name, remembering the answer
+// IDs without a name are shown as numbers, as ls does
+func lookupGroup(id string) (string, error) {
+	if name, ok := groupNames.Load(id); ok {
+		return name.(string), nil
+	}
+	g, err := user.LookupGroupId(id)
+	if errors.As(err, new(user.UnknownGroupIdError)) {
+		groupNames.Store(id, id)
+		return id, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	groupNames.Store(id, g.Name)
+	return g.Name, nil
+}
+
+// Collects entries of a listing that share the same (device, inode) pair
+// Subdirectory listings are searched too, so links spread across a tree are found
+// Only groups with more than one member are returned, in order of first appearance
+func HardLinkGroups(files []FileInfo) [][]FileInfo {
+	var result [][]FileInfo
+	var order []DevIno
+	groups := make(map[DevIno][]FileInfo)
+
+	collectHardLinks(files, groups, &order)
+
+	for _, key := range order {
+		if len(groups[key]) > 1 {
+			result = append(result, groups[key])
+		}
+	}
+	return result
+}
+
+// Walks a listing, bucketing entries by their (device, inode) pair
+func collectHardLinks(files []FileInfo, groups map[DevIno][]FileInfo, order *[]DevIno) {
+	for i := range files {
+		// Directories can't be hard-linked; '.' and '..' only alias them
+		if files[i].Meta.Mode.IsDir() {
+			if len(files[i].RecursiveList) > 0 {
+				collectHardLinks(files[i].RecursiveList, groups, order)
+			}
+			continue
+		}
+
+		key := DevIno{Device: files[i].Meta.Device, Inode: files[i].Meta.Inode}
+		if _, seen := groups[key]; !seen {
+			*order = append(*order, key)
+		}
+		groups[key] = append(groups[key], files[i])
+	}
+}
+
 func IsExecutable(fileInfo os.FileInfo) bool {
 	mode := fileInfo.Mode()
 	return mode&0o100 != 0 || mode&0o010 != 0 || mode&0o001 != 0
@@ -5,8 +5,11 @@ package internal
 
 import (
 	"errors"
-	"runtime"
+	"path"
+	"slices"
+	"strconv"
 	"strings"
+	"unicode/utf8"
 )
 
 // Assigns arguments as valid flags and path
@@ -81,9 +84,254 @@ func SortArgs(args []string) (string, string, error) {
 	return flag, path, err
 }
 
+// Parses every argument into listing options and paths
+// Flags must come before paths; several flag arguments may be given,
+// including long options such as '--format=json'
+func ParseArgs(args []string) (Options, []string, error) {
+	opts, paths, err := parseArgs(args)
+	if err != nil {
+		return Options{}, nil, err
+	}
+
+	// Set path to current directory if none are given
+	// An --archive counts as a path, so alone it lists just the archive
+	if len(paths) == 0 && len(opts.Archives) == 0 && !opts.Help && !opts.Version {
+		paths = append(paths, ".")
+	}
+	return opts, paths, nil
+}
+
+// Same as ParseArgs, but returns only the paths that were given
+func parseArgs(args []string) (Options, []string, error) {
+	var opts Options
+	var paths []string
+	var err error
+
+	// Clean arguments, trim empty strings
+	args = CleanArgs(args)
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+
+		// Everything after '--' is a path, even if it starts with '-'
+		if arg == "--" {
+			for _, rest := range args[i+1:] {
+				_, err = IsValidPath(rest)
+				if err != nil {
+					return Options{}, nil, err
+				}
+				paths = append(paths, rest)
+			}
+			break
+		}
+
+		// Flags after a path are rejected, as in SortArgs
+		if strings.HasPrefix(arg, "-") && len(paths) > 0 {
+			err = errors.New("invalid format: check argument arrangement\nwe recommend: ./run_my_ls.sh [valid flag] [valid path]")
+			return Options{}, nil, err
+		}
+
+		// Long options carry their value after '=', or in the next argument, as in '--sort size'
+		if strings.HasPrefix(arg, "--") {
+			if !strings.Contains(arg, "=") {
+				if matches := LookupLongOption(arg[2:]); len(matches) == 1 && matches[0].Arg != "" && !matches[0].OptionalArg() && i+1 < len(args) {
+					i++
+					arg += "=" + args[i]
+				}
+			}
+
+			err = ParseLongOption(arg, &opts)
+			if err != nil {
+				return Options{}, nil, err
+			}
+
+			// --help and --version act at once, as in coreutils, whatever follows them
+			if opts.Help || opts.Version {
+				return opts, nil, nil
+			}
+			continue
+		}
+
+		// Short flags may be clustered, as in '-lRa'
+		// A flag taking an argument, like '-I', takes the rest of the cluster or the next argument
+		if strings.HasPrefix(arg, "-") && len(arg) > 1 {
+			for j, char := range arg[1:] {
+				flag, ok := LookupShortFlag(char)
+				if !ok {
+					return Options{}, nil, &OptionError{Option: "-" + string(char), Err: ErrInvalidOption}
+				}
+				if flag.Arg == "" {
+					ApplyFlag(string(char), &opts)
+					continue
+				}
+
+				value := arg[1+j+utf8.RuneLen(char):]
+				if value == "" {
+					if i+1 >= len(args) {
+						return Options{}, nil, &OptionError{Option: "-" + string(char), Err: ErrMissingArgument}
+					}
+					i++
+					value = args[i]
+				}
+				err = applyOption(flag, "-"+string(char), value, &opts)
+				if err != nil {
+					return Options{}, nil, err
+				}
+				break
+			}
+			continue
+		}
+
+		_, err = IsValidPath(arg)
+		if err != nil {
+			return Options{}, nil, err
+		}
+		paths = append(paths, arg)
+	}
+	return opts, paths, err
+}
+
+// Applies a long option, such as '--format=json', to the options
+// Like getopt_long, any unambiguous prefix names an option: '--rev' is '--reverse'
+func ParseLongOption(arg string, opts *Options) error {
+	name, value, hasValue := strings.Cut(strings.TrimPrefix(arg, "--"), "=")
+
+	matches := LookupLongOption(name)
+	if len(matches) == 0 {
+		return &OptionError{Option: arg, Err: ErrInvalidOption}
+	}
+	if len(matches) > 1 {
+		var possibilities []string
+		for _, match := range matches {
+			possibilities = append(possibilities, "--"+match.Long)
+		}
+		return &OptionError{Option: "--" + name, Choices: possibilities, Err: ErrAmbiguousOption}
+	}
+
+	flag := matches[0]
+	option := "--" + flag.Long
+	if flag.Arg == "" && hasValue {
+		return &OptionError{Option: option, Err: ErrUnexpectedArgument}
+	}
+	if flag.Arg != "" && !flag.OptionalArg() && !hasValue {
+		return &OptionError{Option: option, Err: ErrMissingArgument}
+	}
+	return applyOption(flag, option, value, opts)
+}
+
+// Applies an option from Flags, given as 'option', with its argument, if it takes one
+func applyOption(flag Flag, option, value string, opts *Options) error {
+	switch flag.Long {
+	case "format":
+		switch value {
+		case "long", "verbose":
+			opts.Long = true
+			opts.Format = ""
+		case "json", "ndjson", "csv", "tsv", "tree":
+			opts.Long = false
+			opts.Format = value
+		default:
+			return &OptionError{Option: option, Value: value, Choices: []string{"long", "verbose", "json", "ndjson", "csv", "tsv", "tree"}, Err: ErrInvalidArgument}
+		}
+	case "quoting-style":
+		if !slices.Contains(QuotingStyles, value) {
+			return &OptionError{Option: option, Value: value, Choices: QuotingStyles, Err: ErrInvalidArgument}
+		}
+		opts.QuotingStyle = value
+	case "sort":
+		if !slices.Contains(SortModes, value) {
+			return &OptionError{Option: option, Value: value, Choices: SortModes, Err: ErrInvalidArgument}
+		}
+		SetSort(value, opts)
+	case "ignore", "hide":
+		patterns := &opts.Ignore
+		if flag.Long == "hide" {
+			patterns = &opts.HidePatterns
+		}
+		if err := AddPattern(patterns, value); err != nil {
+			return &OptionError{Option: option, Value: value, Detail: path.ErrBadPattern.Error(), Err: ErrInvalidArgument}
+		}
+	case "archive":
+		if value == "" {
+			return &OptionError{Option: option, Err: ErrMissingArgument}
+		}
+		opts.Archives = append(opts.Archives, value)
+	case "color":
+		// The words coreutils accepts for each choice; a bare --color means always
+		switch value {
+		case "", "always", "yes", "force":
+			opts.NoColor, opts.ColorAuto = false, false
+		case "never", "no", "none":
+			opts.NoColor, opts.ColorAuto = true, false
+		case "auto", "tty", "if-tty":
+			opts.ColorAuto = true
+		default:
+			return &OptionError{Option: option, Value: value, Choices: []string{"always", "yes", "force", "never", "no", "none", "auto", "tty", "if-tty"}, Err: ErrInvalidArgument}
+		}
+	case "hard-links":
+		opts.HardLinks = true
+	case "group-directories-first":
+		opts.GroupDirectoriesFirst = true
+	case "gitignore":
+		opts.Hide = NewGitIgnoreMatcher().Hide
+	case "tree":
+		opts.Format = "tree"
+	case "max-depth":
+		depth, err := strconv.Atoi(value)
+		if err != nil || depth < 1 {
+			return &OptionError{Option: option, Value: value, Detail: "expected a positive whole number", Err: ErrInvalidArgument}
+		}
+		opts.MaxDepth = depth
+	case "jobs":
+		jobs, err := strconv.Atoi(value)
+		if err != nil || jobs < 1 {
+			return &OptionError{Option: option, Value: value, Detail: "expected a positive whole number", Err: ErrInvalidArgument}
+		}
+		opts.Jobs = jobs
+	case "fields":
+		opts.Fields = nil
+		for _, field := range strings.Split(value, ",") {
+			if _, ok := CSVFields[field]; !ok {
+				var fields []string
+				for name := range CSVFields {
+					fields = append(fields, name)
+				}
+				slices.Sort(fields)
+				return &OptionError{Option: option, Value: field, Choices: fields, Err: ErrInvalidArgument}
+			}
+			opts.Fields = append(opts.Fields, field)
+		}
+	case "no-config":
+		opts.NoConfig = true
+	case "help":
+		opts.Help = true
+	case "version":
+		opts.Version = true
+	default:
+		// The rest are spellings of short flags, such as '--all' for '-a'
+		ApplyFlag(string(flag.Short), opts)
+	}
+	return nil
+}
+
+// Validates a glob pattern, as used by path.Match, and appends it to the list
+func AddPattern(patterns *[]string, pattern string) error {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return errors.New("invalid pattern '" + pattern + "': " + err.Error())
+	}
+	*patterns = append(*patterns, pattern)
+	return nil
+}
+
 func IsValidFlag(arg string) (bool, error) {
 	var err error
 
+	// Long options are valid if they name exactly one option, as ParseLongOption accepts them
+	if strings.HasPrefix(arg, "--") {
+		err = ParseLongOption(arg, &Options{})
+		return err == nil, err
+	}
+
 	// A valid flag is at least two characters long
 	if len(arg) < 2 {
 		err = errors.New("flag is empty")
@@ -97,108 +345,102 @@ func IsValidFlag(arg string) (bool, error) {
 			return false, err
 		}
 
-		// Check for non-valid flag characters after '-
-		if i != 0 && !(char == 'R' || char == 'l' || char == 'a' || char == 't' || char == 'r') {
-			err = errors.New("illegal character: flag has invalid character(s)")
+		// Check every character after '-' names a flag, reporting the first that doesn't
+		if _, ok := LookupShortFlag(char); i != 0 && !ok {
+			err = &OptionError{Option: "-" + string(char), Err: ErrInvalidOption}
 			return false, err
 		}
 	}
 	return true, err
 }
 
-func IsValidPath(arg string) (bool, error) {
-	var err error
-	// A valid path is a non-empty string
-	if len(arg) == 0 {
-		err = errors.New("path is empty")
-		return false, err
-	}
+// Translates a validated flag into listing options
+func ParseFlag(flag string) Options {
+	var opts Options
 
-	// Check for problematic leading / trailing characters
-	if strings.HasPrefix(arg, "-") {
-		err = errors.New("illegal character: leading '-'")
-		return false, err
+	if strings.HasPrefix(flag, "--") {
+		ParseLongOption(flag, &opts)
+		return opts
 	}
+	ApplyFlag(flag, &opts)
+	return opts
+}
 
-	// Identify illegal character based on operrating system
-	system := runtime.GOOS
-	if system == "windows" {
-		illegalChars := []string{"<", ">", ":", "\"", "/", "|", "?", "*"}
-
-		// Check for illegal characters
-		for i := range illegalChars {
-			if strings.Contains(arg, illegalChars[i]) {
-				err = errors.New("illegal character: path contains " + illegalChars[i])
-				return false, err
-			}
-		}
-	} else {
-		illegalSep := "\\"
-		if strings.Contains(arg, illegalSep) {
-			err = errors.New("illegal character: path contains '\\'")
-			return false, err
+// Switches on the options named by each character of a validated flag
+func ApplyFlag(flag string, opts *Options) {
+	for _, char := range strings.TrimPrefix(flag, "-") {
+		switch char {
+		case 'l':
+			// Same as --format=long, so whichever format comes last wins
+			opts.Long = true
+			opts.Format = ""
+		case 'R':
+			opts.Recursive = true
+		case 'a':
+			opts.All = true
+		case 'A':
+			opts.AlmostAll = true
+		case 'B':
+			opts.IgnoreBackups = true
+		case 'd':
+			opts.Directory = true
+		case 'U':
+			SetSort("none", opts)
+		case 'f':
+			opts.All = true
+			opts.NoColor, opts.ColorAuto = true, false
+			SetSort("none", opts)
+		case 'r':
+			opts.Reverse = true
+		case 't':
+			SetSort("time", opts)
+		case 'S':
+			SetSort("size", opts)
+		case 'X':
+			SetSort("extension", opts)
+		case 'v':
+			SetSort("version", opts)
+		case 'h':
+			opts.HumanReadable = true
+		case 'i':
+			opts.Inode = true
+		case 'b':
+			opts.QuotingStyle = "escape"
+		case 'q':
+			opts.HideControl = true
+		case 'Q':
+			opts.QuotingStyle = "c"
 		}
 	}
+}
 
-	invalidChars := []string{
-		"\x00", // Null (NUL)
-		"\x01", // Start of Heading (SOH)
-		"\x02", // Start of Text (STX)
-		"\x03", // End of Text (ETX)
-		"\x04", // End of Transmission (EOT)
-		"\x05", // Enquiry (ENQ)
-		"\x06", // Acknowledge (ACK)
-		"\x07", // Bell (BEL)
-		"\x08", // Backspace (BS)
-		"\x09", // Horizontal Tab (HT)
-		"\x0A", // Line Feed (LF)
-		"\x0B", // Vertical Tab (VT)
-		"\x0C", // Form Feed (FF)
-		"\x0D", // Carriage Return (CR)
-		"\x0E", // Shift Out (SO)
-		"\x0F", // Shift In (SI)
-		"\x10", // Data Link Escape (DLE)
-		"\x11", // Device Control 1 (DC1)
-		"\x12", // Device Control 2 (DC2)
-		"\x13", // Device Control 3 (DC3)
-		"\x14", // Device Control 4 (DC4)
-		"\x15", // Negative Acknowledge (NAK)
-		"\x16", // Synchronous Idle (SYN)
-		"\x17", // End of Transmission Block (ETB)
-		"\x18", // Cancel (CAN)
-		"\x19", // End of Medium (EM)
-		"\x1A", // Substitute (SUB)
-		"\x1B", // Escape (ESC)
-		"\x1C", // File Separator (FS)
-		"\x1D", // Group Separator (GS)
-		"\x1E", // Record Separator (RS)
-		"\x1F", // Unit Separator (US)
-	}
-
-	// Check for illegal characters
-	for i := range invalidChars {
-		if strings.Contains(arg, invalidChars[i]) {
-			err = errors.New("illegal character: path contains " + invalidChars[i])
-			return false, err
-		}
+// Chooses the order of a listing; the last sort flag given wins, as in ls
+// 'none' lists entries in directory order, like -U
+func SetSort(mode string, opts *Options) {
+	opts.Unsorted = mode == "none"
+	if mode == "none" || mode == "name" {
+		opts.Sort = ""
+	} else {
+		opts.Sort = mode
 	}
+}
 
-	invalidChars = []string{
-		"\n",   // Newline
-		"\t",   // Tab
-		"\b",   // Backspace
-		"\r",   // Carriage Return
-		"\033", // ESC
-		"\x1B", // ESC
-		"\x7F", // DEL
-		" ",    // Shitespace
+// Checks that an argument can name a file at all
+// Linux allows every byte in a path except NUL, so spaces, tabs, backslashes
+// and control characters are all accepted; whether the file exists is left
+// to the filesystem checks made when operands are listed
+func IsValidPath(arg string) (bool, error) {
+	var err error
+	// A valid path is a non-empty string
+	if len(arg) == 0 {
+		err = errors.New("path is empty")
+		return false, err
 	}
-	// Check for more illegal characters
-	for i := range invalidChars {
-		if strings.Contains(arg, invalidChars[i]) {
-			err = errors.New("illegal character: path contains " + invalidChars[i])
-			return false, err
-		}
+
+	// NUL ends a path for the kernel, so no file can be named with one
+	if strings.Contains(arg, "\x00") {
+		err = errors.New("illegal character: path contains NUL")
+		return false, err
 	}
 	return true, err
 }
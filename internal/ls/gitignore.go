@@ -0,0 +1,243 @@
+// This file handles .gitignore-aware listing (--gitignore).
+// Rules are read from .git/info/exclude and every .gitignore between the repository root
+// and the listed directory, then matched in pure Go, so no git binary is needed.
+
+package internal
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// One pattern line of a .gitignore file
+type IgnoreRule struct {
+	Base    string // Directory the rule's file applies to
+	Negate  bool   // Line started with '!', re-including matches
+	DirOnly bool   // Line ended with '/', matching only directories
+	Pattern *regexp.Regexp
+}
+
+// Decides which entries git would ignore, caching rules per directory
+// Safe for use by concurrent scans
+type GitIgnoreMatcher struct {
+	mu      sync.Mutex
+	roots   map[string]string       // directory → repository root, empty outside a repository
+	rules   map[string][]IgnoreRule // directory → rules of its .gitignore
+	ignored map[string]bool         // directory → whether it sits inside an ignored directory
+}
+
+func NewGitIgnoreMatcher() *GitIgnoreMatcher {
+	return &GitIgnoreMatcher{
+		roots:   make(map[string]string),
+		rules:   make(map[string][]IgnoreRule),
+		ignored: make(map[string]bool),
+	}
+}
+
+// Hides dotfiles and every entry git would ignore
+func (m *GitIgnoreMatcher) Hide(dir, name string, isDir bool) bool {
+	return HideDotfiles(dir, name, isDir) || m.Ignored(dir, name, isDir)
+}
+
+// Reports whether git would ignore entry 'name' of directory 'dir'
+// Entries outside a repository are never ignored
+func (m *GitIgnoreMatcher) Ignored(dir, name string, isDir bool) bool {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return false
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	root := m.findRoot(dir)
+	if root == "" {
+		return false
+	}
+
+	// Contents of an ignored directory are ignored too, whatever their own rules say
+	if m.insideIgnored(root, dir) {
+		return true
+	}
+	return m.match(root, dir, name, isDir)
+}
+
+// Applies every rule that reaches 'dir', the last match deciding
+func (m *GitIgnoreMatcher) match(root, dir, name string, isDir bool) bool {
+	var ignored bool
+	full := filepath.Join(dir, name)
+
+	for _, rule := range m.rulesFor(root, dir) {
+		if rule.DirOnly && !isDir {
+			continue
+		}
+		rel, err := filepath.Rel(rule.Base, full)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		if rule.Pattern.MatchString(filepath.ToSlash(rel)) {
+			ignored = !rule.Negate
+		}
+	}
+	return ignored
+}
+
+// Reports whether 'dir' or one of its ancestors below 'root' is ignored
+func (m *GitIgnoreMatcher) insideIgnored(root, dir string) bool {
+	if dir == root {
+		return false
+	}
+	if result, ok := m.ignored[dir]; ok {
+		return result
+	}
+
+	parent := filepath.Dir(dir)
+	result := m.insideIgnored(root, parent) || m.match(root, parent, filepath.Base(dir), true)
+	m.ignored[dir] = result
+	return result
+}
+
+// Finds the repository holding 'dir' by looking for '.git' in it and its parents
+func (m *GitIgnoreMatcher) findRoot(dir string) string {
+	if root, ok := m.roots[dir]; ok {
+		return root
+	}
+
+	var root string
+	if _, err := os.Lstat(filepath.Join(dir, ".git")); err == nil {
+		root = dir
+	} else if parent := filepath.Dir(dir); parent != dir {
+		root = m.findRoot(parent)
+	}
+	m.roots[dir] = root
+	return root
+}
+
+// Gathers rules in the order git weighs them: .git/info/exclude first,
+// then each .gitignore from the root down to 'dir'
+func (m *GitIgnoreMatcher) rulesFor(root, dir string) []IgnoreRule {
+	result := m.load(filepath.Join(root, ".git", "info", "exclude"), root)
+
+	rel, _ := filepath.Rel(root, dir)
+	current := root
+	result = append(result, m.load(filepath.Join(current, ".gitignore"), current)...)
+	if rel != "." {
+		for _, part := range strings.Split(rel, string(filepath.Separator)) {
+			current = filepath.Join(current, part)
+			result = append(result, m.load(filepath.Join(current, ".gitignore"), current)...)
+		}
+	}
+	return result
+}
+
+// Reads a rule file once, remembering its rules; missing files hold no rules
+func (m *GitIgnoreMatcher) load(file, base string) []IgnoreRule {
+	if rules, ok := m.rules[file]; ok {
+		return rules
+	}
+
+	var rules []IgnoreRule
+	handle, err := os.Open(file)
+	if err == nil {
+		scanner := bufio.NewScanner(handle)
+		for scanner.Scan() {
+			if rule, ok := ParseIgnoreRule(scanner.Text(), base); ok {
+				rules = append(rules, rule)
+			}
+		}
+		handle.Close()
+	}
+
+	m.rules[file] = rules
+	return rules
+}
+
+// Parses one .gitignore line into a rule applying below 'base'
+// Blank lines and comments give no rule
+func ParseIgnoreRule(line, base string) (IgnoreRule, bool) {
+	rule := IgnoreRule{Base: base}
+
+	// Trailing spaces are dropped unless escaped with a backslash
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, "\\ ") {
+		line = line[:len(line)-1]
+	}
+	if line == "" || strings.HasPrefix(line, "#") {
+		return rule, false
+	}
+
+	if strings.HasPrefix(line, "!") {
+		rule.Negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, "\\!") || strings.HasPrefix(line, "\\#") {
+		line = line[1:]
+	}
+
+	if strings.HasSuffix(line, "/") {
+		rule.DirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return rule, false
+	}
+
+	// A slash anywhere but the end ties the pattern to the .gitignore's directory
+	// Without one, the pattern matches at any depth
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+
+	expression := globToRegexp(line)
+	if !anchored {
+		expression = "(?:.*/)?" + expression
+	}
+
+	pattern, err := regexp.Compile("^" + expression + "$")
+	if err != nil {
+		return rule, false
+	}
+	rule.Pattern = pattern
+	return rule, true
+}
+
+// Translates a gitignore glob into a regular expression
+// '*' and '?' never cross '/', while '**' spans whole directories
+func globToRegexp(glob string) string {
+	var builder strings.Builder
+
+	for i := 0; i < len(glob); i++ {
+		char := glob[i]
+		switch {
+		case strings.HasPrefix(glob[i:], "**/") && (i == 0 || glob[i-1] == '/'):
+			builder.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**") && i+2 == len(glob) && (i == 0 || glob[i-1] == '/'):
+			builder.WriteString(".*")
+			i++
+		case char == '*':
+			builder.WriteString("[^/]*")
+		case char == '?':
+			builder.WriteString("[^/]")
+		case char == '\\' && i+1 < len(glob):
+			i++
+			builder.WriteString(regexp.QuoteMeta(string(glob[i])))
+		case char == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				builder.WriteString("\\[")
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			builder.WriteString("[" + strings.ReplaceAll(class, "\\", "\\\\") + "]")
+			i += end + 1
+		default:
+			builder.WriteString(regexp.QuoteMeta(string(char)))
+		}
+	}
+	return builder.String()
+}
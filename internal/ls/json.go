@@ -0,0 +1,145 @@
+// This file handles machine-readable output (--format=json and --format=ndjson).
+// Each entry becomes one object built from the metadata gathered by RetrieveMetaData,
+// so tooling no longer has to screen-scrape the -l strings.
+
+package internal
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// One listing entry, as emitted by the JSON formats
+type JSONEntry struct {
+	Name         string `json:"name"`
+	Path         string `json:"path"`
+	Dir          string `json:"dir"`
+	Type         string `json:"type"`
+	Mode         string `json:"mode"`
+	ModeSymbolic string `json:"mode_symbolic"`
+	Size         int64  `json:"size"`
+	Blocks       int64  `json:"blocks"`
+	Nlink        int    `json:"nlink"`
+	Owner        string `json:"owner"`
+	UID          uint32 `json:"uid"`
+	Group        string `json:"group"`
+	GID          uint32 `json:"gid"`
+	AccessTime   string `json:"atime"`
+	ModTime      string `json:"mtime"`
+	ChangeTime   string `json:"ctime"`
+	LinkTarget   string `json:"link_target,omitempty"`
+	Inode        uint64 `json:"inode"`
+}
+
+// Converts a listing entry into its JSON representation
+func NewJSONEntry(file FileInfo) JSONEntry {
+	meta := file.Meta
+
+	return JSONEntry{
+		Name:         file.Name,
+		Path:         file.Path,
+		Dir:          file.Dir,
+		Type:         FileType(meta.Mode),
+		Mode:         OctalMode(meta.Mode),
+		ModeSymbolic: SymbolicMode(meta.Mode),
+		Size:         meta.Size,
+		Blocks:       meta.Blocks,
+		Nlink:        meta.HardLinkCount,
+		Owner:        meta.UserID,
+		UID:          meta.UID,
+		Group:        meta.GroupID,
+		GID:          meta.GID,
+		AccessTime:   meta.AccessTime.Format(time.RFC3339Nano),
+		ModTime:      meta.ModTime.Format(time.RFC3339Nano),
+		ChangeTime:   meta.ChangeTime.Format(time.RFC3339Nano),
+		LinkTarget:   meta.LinkTarget,
+		Inode:        meta.Inode,
+	}
+}
+
+// Writes the listing as a single JSON array
+// With -R, entries of subdirectories follow their parent directory's entry
+func RenderJSON(w io.Writer, files []FileInfo, opts Options) error {
+	entries := []JSONEntry{}
+	collectJSONEntries(files, opts, &entries)
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(entries)
+}
+
+// Writes the listing as newline-delimited JSON, one object per line
+// Suited to streaming large -R listings, since no enclosing array is needed
+func RenderNDJSON(w io.Writer, files []FileInfo, opts Options) error {
+	encoder := json.NewEncoder(w)
+
+	for i := range files {
+		if err := encoder.Encode(NewJSONEntry(files[i])); err != nil {
+			return err
+		}
+		if opts.Recursive && len(files[i].RecursiveList) > 0 {
+			if err := RenderNDJSON(w, files[i].RecursiveList, opts); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Writes a directory's entries as newline-delimited JSON while it is read
+// Unsorted listings are written entry by entry, so memory use does not grow with the directory;
+// sorted ones a directory at a time. With -R, each subdirectory's entries follow its own,
+// written out as the scan reaches them rather than once the whole tree is read
+// Directories that can't be read are returned as 'cannot open directory' errors and the rest
+// still written; the error return is kept for failed writes
+func StreamNDJSON(w io.Writer, path string, opts Options) ([]error, error) {
+	encoder := json.NewEncoder(w)
+	var errs []error
+	var writeErr error
+
+	emit := func(file FileInfo) error {
+		if writeErr = encoder.Encode(NewJSONEntry(file)); writeErr != nil {
+			return writeErr
+		}
+		if !opts.Recursive || !IsDescended(file) {
+			return nil
+		}
+		var subErrs []error
+		subErrs, writeErr = StreamNDJSON(w, file.Path, opts)
+		errs = append(errs, subErrs...)
+		return writeErr
+	}
+
+	var err error
+	if opts.Unsorted {
+		err = StreamDirectory(path, opts, emit)
+	} else {
+		var files []FileInfo
+		if files, err = ListDirectory(path, opts); err == nil {
+			for i := range files {
+				if err = emit(files[i]); err != nil {
+					break
+				}
+			}
+		}
+	}
+
+	if writeErr != nil {
+		return errs, writeErr
+	}
+	if err != nil {
+		errs = append(errs, &OperandError{Op: "open directory", Path: path, Err: err})
+	}
+	return errs, FlushSection(w)
+}
+
+// Flattens the listing, descending into subdirectories with -R
+func collectJSONEntries(files []FileInfo, opts Options, entries *[]JSONEntry) {
+	for i := range files {
+		*entries = append(*entries, NewJSONEntry(files[i]))
+		if opts.Recursive && len(files[i].RecursiveList) > 0 {
+			collectJSONEntries(files[i].RecursiveList, opts, entries)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+// This file handles quoting and escaping of file names (-b, -q, -Q, --quoting-style).
+// Names may hold newlines, escape sequences or other control bytes that would break
+// the terminal or spoof output, so each style renders them harmlessly, as GNU ls does.
+
+package internal
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Quoting styles accepted by --quoting-style
+var QuotingStyles = []string{"literal", "shell", "shell-always", "shell-escape", "shell-escape-always", "c", "escape"}
+
+// Characters that make a name need quoting before a shell can read it
+const shellSpecial = " \t\n!\"$&'()*;<=>?[\\]^`{|}"
+
+// Characters that only need quoting at the start of a name
+const shellSpecialLeading = "#~"
+
+// Renders a name in the given quoting style
+// With hideControl (-q), nonprintable characters become '?' in the styles that don't escape them
+func QuoteName(name, style string, hideControl bool) string {
+	switch style {
+	case "c":
+		return "\"" + escapeName(name, true) + "\""
+	case "escape":
+		return escapeName(name, false)
+	case "shell", "shell-always":
+		if hideControl {
+			name = hideNonprintable(name)
+		}
+		return shellQuote(name, style == "shell-always")
+	case "shell-escape", "shell-escape-always":
+		return shellEscape(name, style == "shell-escape-always")
+	}
+
+	// Literal style prints names as they are
+	if hideControl {
+		return hideNonprintable(name)
+	}
+	return name
+}
+
+// Replaces each nonprintable character with '?'
+func hideNonprintable(name string) string {
+	var builder strings.Builder
+
+	for len(name) > 0 {
+		r, size := utf8.DecodeRuneInString(name)
+		if isPrintable(r, size) {
+			builder.WriteString(name[:size])
+		} else {
+			builder.WriteByte('?')
+		}
+		name = name[size:]
+	}
+	return builder.String()
+}
+
+// Reports whether a decoded rune can be shown as it is
+func isPrintable(r rune, size int) bool {
+	return !(r == utf8.RuneError && size == 1) && unicode.IsPrint(r)
+}
+
+// Backslash-escapes a name, as in C string literals
+// Double quotes are only escaped when the name is wrapped in them,
+// spaces only when it isn't, so the result stays one word either way
+func escapeName(name string, quoted bool) string {
+	var builder strings.Builder
+
+	for len(name) > 0 {
+		r, size := utf8.DecodeRuneInString(name)
+		switch {
+		case r == '\\':
+			builder.WriteString("\\\\")
+		case r == '"' && quoted:
+			builder.WriteString("\\\"")
+		case r == ' ' && !quoted:
+			builder.WriteString("\\ ")
+		case isPrintable(r, size):
+			builder.WriteString(name[:size])
+		default:
+			builder.WriteString(escapeBytes(name[:size]))
+		}
+		name = name[size:]
+	}
+	return builder.String()
+}
+
+// Escapes nonprintable bytes, using C's short forms where they exist
+func escapeBytes(bytes string) string {
+	short := map[byte]string{
+		'\a': "\\a", '\b': "\\b", '\f': "\\f", '\n': "\\n",
+		'\r': "\\r", '\t': "\\t", '\v': "\\v",
+	}
+
+	var builder strings.Builder
+	for i := 0; i < len(bytes); i++ {
+		if escaped, ok := short[bytes[i]]; ok {
+			builder.WriteString(escaped)
+		} else {
+			builder.WriteString(fmt.Sprintf("\\%03o", bytes[i]))
+		}
+	}
+	return builder.String()
+}
+
+// Reports whether a shell would split or expand the name unless quoted
+func needsShellQuoting(name string) bool {
+	return name == "" || strings.ContainsAny(name, shellSpecial) || strings.ContainsAny(name[:1], shellSpecialLeading)
+}
+
+// Quotes a name so a shell reads it back unchanged
+// Single quotes are preferred; names holding a single quote and
+// nothing a shell expands inside double quotes use double quotes instead
+func shellQuote(name string, always bool) string {
+	if !always && !needsShellQuoting(name) {
+		return name
+	}
+	if strings.Contains(name, "'") && !strings.ContainsAny(name, "\"$`\\!") {
+		return "\"" + name + "\""
+	}
+	return "'" + strings.ReplaceAll(name, "'", "'\\''") + "'"
+}
+
+// Quotes a name like shellQuote, writing nonprintable characters as $'...' sequences
+func shellEscape(name string, always bool) string {
+	if !strings.ContainsFunc(name, func(r rune) bool { return !unicode.IsPrint(r) }) && utf8.ValidString(name) {
+		return shellQuote(name, always)
+	}
+
+	// Alternate between quoted printable runs and escaped nonprintable runs
+	var builder strings.Builder
+	for len(name) > 0 {
+		end := 0
+		for end < len(name) {
+			r, size := utf8.DecodeRuneInString(name[end:])
+			if !isPrintable(r, size) {
+				break
+			}
+			end += size
+		}
+		if end > 0 {
+			builder.WriteString("'" + strings.ReplaceAll(name[:end], "'", "'\\''") + "'")
+			name = name[end:]
+			continue
+		}
+
+		for end < len(name) {
+			r, size := utf8.DecodeRuneInString(name[end:])
+			if isPrintable(r, size) {
+				break
+			}
+			end += size
+		}
+		builder.WriteString("$'" + escapeBytes(name[:end]) + "'")
+		name = name[end:]
+	}
+	return builder.String()
+}
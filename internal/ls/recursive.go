@@ -1,5 +1,103 @@
 // This file handles recursive directory traversal (-R flag).
 // It implements logic to list files in subdirectories while maintaining correct formatting and indentation for easier reading,
 // just like the real ls -R command.
+// Subdirectories are read by a bounded pool of workers (--jobs), which hides filesystem latency
+// on network mounts; each result lands in its parent's sorted slot, so output order never changes.
 
 package internal
+
+import (
+	"context"
+	"runtime"
+	"sync"
+)
+
+// Reads a directory tree, with at most a fixed number of directories read at once
+type Scanner struct {
+	opts  Options
+	slots chan struct{} // One token per extra worker; the calling goroutine is always one
+}
+
+// Prepares a scanner allowing opts.Jobs concurrent reads, or one per CPU if unset
+func NewScanner(opts Options) *Scanner {
+	jobs := opts.Jobs
+	if jobs < 1 {
+		jobs = runtime.NumCPU()
+	}
+	return &Scanner{opts: opts, slots: make(chan struct{}, jobs-1)}
+}
+
+// Lists a directory and, into each entry's RecursiveList, all its subdirectories
+// With --max-depth, directories that deep are listed without reading their contents
+// Subdirectories that can't be read are reported in tree order and left with a nil RecursiveList;
+// those that were read have a non-nil one, even if empty, so -R only heads the sections it can show
+func (s *Scanner) Scan(path string) ([]FileInfo, []error) {
+	return s.ScanContext(context.Background(), path)
+}
+
+// Same as Scan, but stops reading subdirectories once ctx is cancelled
+// Those not yet read are left with a nil RecursiveList, and ctx.Err() is left for the caller to check
+func (s *Scanner) ScanContext(ctx context.Context, path string) ([]FileInfo, []error) {
+	files, err := ListDirectory(path, s.opts)
+	if err != nil {
+		return nil, []error{&OperandError{Op: "open directory", Path: path, Err: err}}
+	}
+	return files, s.scanChildren(ctx, files, 1)
+}
+
+// Reports whether -R descends into an entry
+// Only directories qualify, never '.' and '..', nor links to directories
+func IsDescended(file FileInfo) bool {
+	return file.Meta.Mode.IsDir() && file.Name != "." && file.Name != ".."
+}
+
+// Lists the subdirectories among 'files', which are 'depth' levels below the scanned directory
+func (s *Scanner) scanChildren(ctx context.Context, files []FileInfo, depth int) []error {
+	if s.opts.MaxDepth > 0 && depth >= s.opts.MaxDepth {
+		return nil
+	}
+
+	var wg sync.WaitGroup
+	childErrs := make([][]error, len(files))
+
+	for i := range files {
+		if !IsDescended(files[i]) {
+			continue
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		// Hand the subdirectory to a new worker if one is free,
+		// otherwise read it here, so nested scans can't wait on each other
+		select {
+		case s.slots <- struct{}{}:
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				defer func() { <-s.slots }()
+				childErrs[i] = s.scanDirectory(ctx, &files[i], depth)
+			}(i)
+		default:
+			childErrs[i] = s.scanDirectory(ctx, &files[i], depth)
+		}
+	}
+	wg.Wait()
+
+	// Each worker reported into its own slot, so errors keep the order of the listing
+	var errs []error
+	for i := range childErrs {
+		errs = append(errs, childErrs[i]...)
+	}
+	return errs
+}
+
+// Reads a subdirectory, at 'depth' levels below the scanned one, into its RecursiveList
+func (s *Scanner) scanDirectory(ctx context.Context, dir *FileInfo, depth int) []error {
+	files, err := ListDirectory(dir.Path, s.opts)
+	if err != nil {
+		return []error{&OperandError{Op: "open directory", Path: dir.Path, Err: err}}
+	}
+	dir.RecursiveList = files
+	return s.scanChildren(ctx, files, depth+1)
+}
@@ -0,0 +1,343 @@
+// This file holds the my-ls command itself, apart from the process it runs in.
+// main only hands it the command line and standard streams, so the whole command
+// can also be run in-process, as the integration tests do.
+
+package internal
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+)
+
+// Runs my-ls with the arguments following the program name, returning its exit status
+// Environment variables are read through 'env', such as os.Getenv, never from the process
+// Status 2 means the command line was invalid or an operand could not be listed, as in ls;
+// 1 means output failed
+func Run(args []string, env func(string) string, stdout, stderr io.Writer) int {
+	// Report errors as coreutils does: 'my-ls: message'
+	logger := log.New(stderr, "my-ls: ", 0)
+
+	// Extract options and paths from user arguments
+	// Handle errors, if encountered
+	// Mistakes on the command line exit with status 2, as in coreutils;
+	// a bad option also points at --help
+	opts, paths, err := ParseArgs(args)
+
+	// Defaults from the config file and MY_LS_OPTIONS go ahead of the command line,
+	// which is parsed first so that its own mistakes are the ones reported
+	if err == nil && !opts.NoConfig && !opts.Help && !opts.Version {
+		var defaults []string
+		defaults, err = DefaultArgs(env)
+		if err == nil && len(defaults) > 0 {
+			opts, paths, err = ParseArgs(append(defaults, args...))
+		}
+	}
+	if err != nil {
+		logger.Print(err)
+		var optionErr *OptionError
+		if errors.As(err, &optionErr) {
+			fmt.Fprintln(stderr, "Try 'my-ls --help' for more information.")
+		}
+		return 2
+	}
+	if opts.Help || opts.Version {
+		write := WriteUsage
+		if opts.Version {
+			write = WriteVersion
+		}
+		if err := write(stdout); err != nil {
+			logger.Print(err)
+			return 1
+		}
+		return 0
+	}
+
+	// --color=auto colors names only on terminals, so piped output stays plain
+	if opts.ColorAuto {
+		file, ok := stdout.(*os.File)
+		opts.NoColor = !ok || !IsTerminal(file)
+	}
+
+	// Escape names shell-style on terminals, as modern coreutils do
+	// With -q, nonprintables are shown as '?' instead of being escaped
+	if file, ok := stdout.(*os.File); ok && opts.QuotingStyle == "" && IsTerminal(file) {
+		if opts.HideControl {
+			opts.QuotingStyle = "shell"
+		} else {
+			opts.QuotingStyle = "shell-escape"
+		}
+	}
+
+	// Output is fully buffered and written out once per directory section,
+	// which saves a write call per line on large listings
+	out := bufio.NewWriterSize(stdout, 64*1024)
+
+	var status int
+	if opts.Format == "tree" {
+		status, err = listTrees(out, logger, paths, opts, env)
+	} else {
+		status, err = listOperands(out, logger, paths, opts)
+	}
+
+	// Whatever was listed is still written out when the command fails
+	if flushErr := out.Flush(); err == nil {
+		err = flushErr
+	}
+	if err != nil {
+		logger.Print(err)
+		return 1
+	}
+	return status
+}
+
+// Draws each path as its own tree
+func listTrees(out *bufio.Writer, logger *log.Logger, paths []string, opts Options, env func(string) string) (int, error) {
+	var status int
+
+	connectors := ASCIIConnectors
+	if IsUTF8Locale(env) {
+		connectors = UnicodeConnectors
+	}
+
+	// Like tree(1), -a shows dotfiles but never '.' and '..'
+	opts.AlmostAll = opts.AlmostAll || opts.All
+	opts.All = false
+	opts.Recursive = true
+
+	// Archives are listed like directories, from an index of their members
+	paths, archives := SplitArchives(paths, opts)
+	for _, spec := range opts.Archives {
+		archives = append(archives, ParseArchiveOption(spec))
+	}
+
+	// Operands that can't be listed are reported first, and the rest still drawn
+	files, dirs, errs := SplitOperands(paths, opts)
+	for _, err := range errs {
+		logger.Print(err)
+	}
+	if len(errs) > 0 {
+		status = 2
+	}
+
+	// File operands hold nothing to draw, so they are named on their own, ahead of the trees
+	inodeWidth := InodeWidth(files)
+	for i := range files {
+		if _, err := fmt.Fprintln(out, AlignedEntryName(files[i], opts, inodeWidth)); err != nil {
+			return status, err
+		}
+	}
+	for _, path := range dirs {
+		entries, errs := RetrieveEntries(path, opts)
+		if len(errs) > 0 {
+			status = 2
+			if err := reportErrors(out, logger, errs); err != nil {
+				return status, err
+			}
+		}
+		if err := RenderTree(out, path, entries, opts, connectors); err != nil {
+			return status, err
+		}
+		if err := out.Flush(); err != nil {
+			return status, err
+		}
+	}
+	for _, operand := range archives {
+		files, _, err := RetrieveArchive(operand, opts)
+		if err != nil {
+			logger.Print(err)
+			status = 2
+			continue
+		}
+		if err := RenderTree(out, operand.Spec, files, opts, connectors); err != nil {
+			return status, err
+		}
+		if err := out.Flush(); err != nil {
+			return status, err
+		}
+	}
+	return status, nil
+}
+
+// Lists file operands, then the contents of each directory and archive operand
+func listOperands(out *bufio.Writer, logger *log.Logger, paths []string, opts Options) (int, error) {
+	var status int
+
+	// Archives are listed like directories, from an index of their members
+	paths, archives := SplitArchives(paths, opts)
+	for _, spec := range opts.Archives {
+		archives = append(archives, ParseArchiveOption(spec))
+	}
+
+	// --max-depth only limits the tree view
+	opts.MaxDepth = 0
+
+	// File operands (and directories, with -d) are listed as themselves
+	// Directory operands are listed by their contents
+	// Operands that can't be listed are reported, and the rest still listed
+	files, dirs, errs := SplitOperands(paths, opts)
+
+	// Archive directories get their own sections; other members are listed with the files
+	var archiveDirs []ArchiveOperand
+	var archiveLists [][]FileInfo
+	for _, operand := range archives {
+		entries, isDir, err := RetrieveArchive(operand, opts)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if isDir {
+			archiveDirs = append(archiveDirs, operand)
+			archiveLists = append(archiveLists, entries)
+		} else {
+			files = append(files, entries...)
+		}
+	}
+	SortEntries(files, opts)
+
+	for _, err := range errs {
+		logger.Print(err)
+	}
+	// Exit with status 2 once the listing is done, as ls does for bad operands
+	if len(errs) > 0 {
+		status = 2
+	}
+
+	// NDJSON is written while directories are read, and with -R while the tree is scanned
+	if opts.Format == "ndjson" {
+		if err := RenderNDJSON(out, files, opts); err != nil {
+			return status, err
+		}
+		for _, dir := range dirs {
+			errs, err := StreamNDJSON(out, dir, opts)
+			if len(errs) > 0 {
+				status = 2
+				if err := reportErrors(out, logger, errs); err != nil {
+					return status, err
+				}
+			}
+			if err != nil {
+				return status, err
+			}
+		}
+		for _, entries := range archiveLists {
+			if err := RenderNDJSON(out, entries, opts); err != nil {
+				return status, err
+			}
+		}
+		return status, nil
+	}
+
+	// Machine-readable formats replace the listing entirely
+	if opts.Format != "" {
+		for _, dir := range dirs {
+			entries, errs := RetrieveEntries(dir, opts)
+			for _, err := range errs {
+				logger.Print(err)
+				status = 2
+			}
+			files = append(files, entries...)
+		}
+		for _, entries := range archiveLists {
+			files = append(files, entries...)
+		}
+
+		var err error
+		switch opts.Format {
+		case "json":
+			err = RenderJSON(out, files, opts)
+		case "csv":
+			err = RenderCSV(out, files, opts)
+		case "tsv":
+			err = RenderTSV(out, files, opts)
+		}
+		return status, err
+	}
+
+	// Directories are headed by their path once more than one operand is given, as ls does,
+	// counting operands that could not be listed
+	header := len(files)+len(dirs)+len(archiveDirs)+len(errs) > 1 || opts.Recursive
+	listed := files
+
+	if len(files) > 0 {
+		if err := RenderEntries(out, files, opts); err != nil {
+			return status, err
+		}
+		if err := out.Flush(); err != nil {
+			return status, err
+		}
+	}
+	for i, dir := range dirs {
+		if i > 0 || len(files) > 0 {
+			fmt.Fprintln(out)
+		}
+
+		// Unsorted listings are printed while they are read
+		if CanStream(opts) {
+			err := StreamDirectoryListing(out, dir, opts, header)
+			var operandErr *OperandError
+			if errors.As(err, &operandErr) {
+				logger.Print(err)
+				status = 2
+			} else if err != nil {
+				return status, err
+			}
+			continue
+		}
+
+		// Each directory that can't be read is reported, and the rest of the tree still listed
+		entries, errs := RetrieveEntries(dir, opts)
+		if len(errs) > 0 {
+			status = 2
+			if err := reportErrors(out, logger, errs); err != nil {
+				return status, err
+			}
+		}
+		// A directory that couldn't be read itself gets no section, as in ls
+		if len(errs) > 0 && isOperandError(errs[0], dir) {
+			continue
+		}
+		if err := RenderDirectory(out, dir, entries, opts, header); err != nil {
+			return status, err
+		}
+		listed = append(listed, entries...)
+	}
+
+	// Archive members have no inodes, so they are left out of --hard-links
+	for i, operand := range archiveDirs {
+		if i > 0 || len(dirs) > 0 || len(files) > 0 {
+			fmt.Fprintln(out)
+		}
+		if err := RenderDirectory(out, operand.Spec, archiveLists[i], opts, header); err != nil {
+			return status, err
+		}
+	}
+
+	// With --hard-links, point out entries that are hard links to the same file
+	if opts.HardLinks {
+		if err := PrintHardLinks(out, HardLinkGroups(listed)); err != nil {
+			return status, err
+		}
+	}
+	return status, nil
+}
+
+// Reports errors met while listing, after writing out what was listed before them
+func reportErrors(out *bufio.Writer, logger *log.Logger, errs []error) error {
+	if err := out.Flush(); err != nil {
+		return err
+	}
+	for _, err := range errs {
+		logger.Print(err)
+	}
+	return nil
+}
+
+// Reports whether 'err' is about the operand 'path' itself, rather than something found under it
+func isOperandError(err error, path string) bool {
+	var operandErr *OperandError
+	return errors.As(err, &operandErr) && operandErr.Path == path
+}
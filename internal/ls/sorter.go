@@ -3,3 +3,248 @@
 // By separating this logic, we can ensure flexibility and make it easier to test the sorting independently.
 
 package internal
+
+import (
+	"io/fs"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type ReverseAlpha []FileInfo
+type Alphabetic []FileInfo
+type ByTime []FileInfo
+type BySize []FileInfo
+type ByExtension []FileInfo
+type ByVersion []FileInfo
+
+// Orders accepted by --sort=WORD
+// 'none' lists entries in directory order, like -U
+var SortModes = []string{"name", "none", "size", "time", "version", "extension"}
+
+// Give sort.Sort interface size for sorting
+func (f Alphabetic) Len() int {
+	return len(f)
+}
+
+// Give sorting algoriths parameter for sorting
+func (f Alphabetic) Less(i, j int) bool {
+	return NameLess(f[i].Name, f[j].Name)
+}
+
+// Handle swapping
+func (f Alphabetic) Swap(i, j int) {
+	f[i], f[j] = f[j], f[i]
+}
+
+// Give sort.Sort interface size for sorting
+func (f ReverseAlpha) Len() int {
+	return len(f)
+}
+
+// Give sorting algoriths parameter for sorting
+func (f ReverseAlpha) Less(i, j int) bool {
+	return NameLess(f[j].Name, f[i].Name)
+}
+
+// Handle swapping
+func (f ReverseAlpha) Swap(i, j int) {
+	f[i], f[j] = f[j], f[i]
+}
+
+// Give sort.Sort interface size for sorting
+func (f ByTime) Len() int {
+	return len(f)
+}
+
+// Give sorting algoriths parameter for sorting
+// Newest first; entries changed at the same time are sorted by name
+func (f ByTime) Less(i, j int) bool {
+	if !f[i].Meta.ModTime.Equal(f[j].Meta.ModTime) {
+		return f[i].Meta.ModTime.After(f[j].Meta.ModTime)
+	}
+	return NameLess(f[i].Name, f[j].Name)
+}
+
+// Handle swapping
+func (f ByTime) Swap(i, j int) {
+	f[i], f[j] = f[j], f[i]
+}
+
+// Give sort.Sort interface size for sorting
+func (f BySize) Len() int {
+	return len(f)
+}
+
+// Give sorting algoriths parameter for sorting
+// Largest first; entries of equal size are sorted by name
+func (f BySize) Less(i, j int) bool {
+	if f[i].Meta.Size != f[j].Meta.Size {
+		return f[i].Meta.Size > f[j].Meta.Size
+	}
+	return NameLess(f[i].Name, f[j].Name)
+}
+
+// Handle swapping
+func (f BySize) Swap(i, j int) {
+	f[i], f[j] = f[j], f[i]
+}
+
+// Give sort.Sort interface size for sorting
+func (f ByExtension) Len() int {
+	return len(f)
+}
+
+// Give sorting algoriths parameter for sorting
+// Entries without an extension come first; equal extensions are sorted by name
+func (f ByExtension) Less(i, j int) bool {
+	extI, extJ := Extension(f[i].Name), Extension(f[j].Name)
+	if extI != extJ {
+		return extI < extJ
+	}
+	return NameLess(f[i].Name, f[j].Name)
+}
+
+// Handle swapping
+func (f ByExtension) Swap(i, j int) {
+	f[i], f[j] = f[j], f[i]
+}
+
+// Give sort.Sort interface size for sorting
+func (f ByVersion) Len() int {
+	return len(f)
+}
+
+// Give sorting algoriths parameter for sorting
+func (f ByVersion) Less(i, j int) bool {
+	return VersionLess(f[i].Name, f[j].Name)
+}
+
+// Handle swapping
+func (f ByVersion) Swap(i, j int) {
+	f[i], f[j] = f[j], f[i]
+}
+
+// Compares names alphabetically, ignoring case, as ls does by default
+// Runes are lowercased as they are compared, so sorting allocates nothing
+func NameLess(a, b string) bool {
+	for a != "" && b != "" {
+		runeA, sizeA := utf8.DecodeRuneInString(a)
+		runeB, sizeB := utf8.DecodeRuneInString(b)
+		if runeA, runeB = unicode.ToLower(runeA), unicode.ToLower(runeB); runeA != runeB {
+			return runeA < runeB
+		}
+		a, b = a[sizeA:], b[sizeB:]
+	}
+	return a == "" && b != ""
+}
+
+// Returns the lowercased text after the last '.' of a name, or "" if it has none
+// A leading dot marks a hidden file rather than an extension, as ls -X treats it
+func Extension(name string) string {
+	dot := strings.LastIndexByte(name, '.')
+	if dot <= 0 {
+		return ""
+	}
+	return strings.ToLower(name[dot+1:])
+}
+
+// Compares names as version numbers, so "file2" comes before "file10"
+// Runs of digits are compared by value and everything else byte by byte
+func VersionLess(a, b string) bool {
+	for a != "" && b != "" {
+		if isDigit(a[0]) && isDigit(b[0]) {
+			numA, restA := splitDigits(a)
+			numB, restB := splitDigits(b)
+
+			// Without leading zeros, the longer run is the larger number
+			trimA, trimB := strings.TrimLeft(numA, "0"), strings.TrimLeft(numB, "0")
+			if len(trimA) != len(trimB) {
+				return len(trimA) < len(trimB)
+			}
+			if trimA != trimB {
+				return trimA < trimB
+			}
+			a, b = restA, restB
+			continue
+		}
+
+		if a[0] != b[0] {
+			return a[0] < b[0]
+		}
+		a, b = a[1:], b[1:]
+	}
+	return len(a) < len(b)
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// Splits the leading run of digits off 's'
+func splitDigits(s string) (string, string) {
+	end := 0
+	for end < len(s) && isDigit(s[end]) {
+		end++
+	}
+	return s[:end], s[end:]
+}
+
+// Orders a listing as the sort options ask, reversed with -r, with directories first if asked
+// Unsorted listings keep directory order, and neither -r nor grouping affects them, as in ls
+func SortEntries(files []FileInfo, opts Options) {
+	if opts.Unsorted {
+		return
+	}
+
+	var order sort.Interface
+	switch opts.Sort {
+	case "size":
+		order = BySize(files)
+	case "time":
+		order = ByTime(files)
+	case "extension":
+		order = ByExtension(files)
+	case "version":
+		order = ByVersion(files)
+	default:
+		order = Alphabetic(files)
+	}
+
+	if opts.Reverse {
+		order = sort.Reverse(order)
+	}
+	sort.Stable(order)
+
+	if opts.GroupDirectoriesFirst {
+		GroupDirectories(files, opts)
+	}
+}
+
+// Moves directories, and symbolic links to them, ahead of other entries, as --group-directories-first asks
+// Each group keeps the order it was sorted in, so -r reverses within groups, as in ls
+func GroupDirectories(files []FileInfo, opts Options) {
+	var dirs, others []FileInfo
+	for _, file := range files {
+		if IsDirectoryLike(file, opts) {
+			dirs = append(dirs, file)
+		} else {
+			others = append(others, file)
+		}
+	}
+	copy(files, dirs)
+	copy(files[len(dirs):], others)
+}
+
+// Reports whether an entry is a directory or a symbolic link that resolves to one
+func IsDirectoryLike(file FileInfo, opts Options) bool {
+	if file.Meta.Mode.IsDir() {
+		return true
+	}
+	if file.Meta.Mode&fs.ModeSymlink == 0 {
+		return false
+	}
+	info, err := fs.Stat(opts.Filesystem(), file.Path)
+	return err == nil && info.IsDir()
+}
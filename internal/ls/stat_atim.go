@@ -0,0 +1,13 @@
+//go:build linux || openbsd || dragonfly || solaris || illumos
+
+package internal
+
+import (
+	"syscall"
+	"time"
+)
+
+// Access and status change times of a file, as these systems name them in syscall.Stat_t
+func statTimes(stat *syscall.Stat_t) (atime, ctime time.Time) {
+	return time.Unix(int64(stat.Atim.Sec), int64(stat.Atim.Nsec)), time.Unix(int64(stat.Ctim.Sec), int64(stat.Ctim.Nsec))
+}
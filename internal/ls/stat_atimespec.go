@@ -0,0 +1,13 @@
+//go:build darwin || freebsd || netbsd
+
+package internal
+
+import (
+	"syscall"
+	"time"
+)
+
+// Access and status change times of a file, as the BSDs and macOS name them in syscall.Stat_t
+func statTimes(stat *syscall.Stat_t) (atime, ctime time.Time) {
+	return time.Unix(int64(stat.Atimespec.Sec), int64(stat.Atimespec.Nsec)), time.Unix(int64(stat.Ctimespec.Sec), int64(stat.Ctimespec.Nsec))
+}
@@ -0,0 +1,94 @@
+// This file handles the tree view (--tree).
+// It walks the RecursiveList of each entry, drawing connectors that show how entries nest,
+// down to the depth set by --max-depth, and closes with a count of directories and files.
+
+package internal
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Line-drawing pieces used to show nesting in the tree view
+type TreeConnectors struct {
+	Branch   string // entry with siblings below it
+	Last     string // final entry of a directory
+	Vertical string // continues a branch past nested entries
+	Blank    string // pads below a final entry
+}
+
+// Connectors for UTF-8 terminals
+var UnicodeConnectors = TreeConnectors{"├── ", "└── ", "│   ", "    "}
+
+// Connectors for locales that cannot show box-drawing characters
+var ASCIIConnectors = TreeConnectors{"|-- ", "`-- ", "|   ", "    "}
+
+// Reports whether the locale in the environment uses UTF-8
+// The first of LC_ALL, LC_CTYPE and LANG that is set decides, as with setlocale
+func IsUTF8Locale(getenv func(string) string) bool {
+	for _, name := range []string{"LC_ALL", "LC_CTYPE", "LANG"} {
+		value := getenv(name)
+		if value == "" {
+			continue
+		}
+		value = strings.ToLower(value)
+		return strings.Contains(value, "utf-8") || strings.Contains(value, "utf8")
+	}
+	return false
+}
+
+// Writes the listing of 'root' as a tree, followed by a summary line
+func RenderTree(w io.Writer, root string, files []FileInfo, opts Options, connectors TreeConnectors) error {
+	var dirs, regular int
+
+	if _, err := fmt.Fprintln(w, root); err != nil {
+		return err
+	}
+	if err := writeTreeLevel(w, files, opts, connectors, "", 1, &dirs, &regular); err != nil {
+		return err
+	}
+
+	_, err := fmt.Fprintf(w, "\n%v, %v\n", plural(dirs, "directory", "directories"), plural(regular, "file", "files"))
+	return err
+}
+
+// Writes one directory's entries, then descends until the depth limit is reached
+// With -i, inodes are aligned within each directory, as in a short listing
+func writeTreeLevel(w io.Writer, files []FileInfo, opts Options, connectors TreeConnectors, prefix string, depth int, dirs, regular *int) error {
+	inodeWidth := InodeWidth(files)
+	for i := range files {
+		connector, padding := connectors.Branch, connectors.Vertical
+		if i == len(files)-1 {
+			connector, padding = connectors.Last, connectors.Blank
+		}
+
+		if _, err := fmt.Fprintf(w, "%v%v%v\n", prefix, connector, AlignedEntryName(files[i], opts, inodeWidth)); err != nil {
+			return err
+		}
+
+		if !files[i].Meta.Mode.IsDir() {
+			*regular++
+			continue
+		}
+		*dirs++
+
+		// A max depth of zero leaves the tree unlimited
+		if opts.MaxDepth > 0 && depth >= opts.MaxDepth {
+			continue
+		}
+		err := writeTreeLevel(w, files[i].RecursiveList, opts, connectors, prefix+padding, depth+1, dirs, regular)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Pairs a count with the singular or plural form of a noun
+func plural(count int, singular, plural string) string {
+	if count == 1 {
+		return fmt.Sprintf("%d %v", count, singular)
+	}
+	return fmt.Sprintf("%d %v", count, plural)
+}
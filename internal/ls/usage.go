@@ -0,0 +1,205 @@
+// This file describes every command-line option in one table, from which long options are
+// matched and --help is written, so the usage text can't drift from what is accepted.
+
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"runtime/debug"
+	"strings"
+)
+
+// A command-line option: its short flag, its long name, or both
+type Flag struct {
+	Short rune   // Short flag, such as 'a' for -a; 0 if there is none
+	Long  string // Long name, such as "all" for --all; empty if there is none
+	Arg   string // Placeholder for the option's argument, such as "WORD"; empty if it takes none, "[=WORD]" if it may
+	Help  string // Description shown by --help
+}
+
+// Reports whether the option's argument may be left out, as in '--color'
+// An optional argument must follow '=', so the next argument is never taken for it
+func (f Flag) OptionalArg() bool {
+	return strings.HasPrefix(f.Arg, "[")
+}
+
+// Every option my-ls accepts, in the order --help lists them
+var Flags = []Flag{
+	{'a', "all", "", "do not ignore entries starting with ."},
+	{'A', "almost-all", "", "do not list implied . and .."},
+	{0, "archive", "FILE[:DIR]", "list the members of a tar, tar.gz or zip archive as a directory"},
+	{'b', "escape", "", "print C-style escapes for nonprintable characters"},
+	{'B', "ignore-backups", "", "do not list implied entries ending with ~"},
+	{0, "color", "[=WHEN]", "color names by type: always (the default), auto or never"},
+	{'d', "directory", "", "list directories themselves, not their contents"},
+	{'f', "", "", "list all entries in directory order, without color"},
+	{0, "fields", "LIST", "columns of --format=csv and tsv, such as name,size,mtime"},
+	{0, "format", "WORD", "long or verbose (-l), json, ndjson, csv, tsv or tree"},
+	{0, "gitignore", "", "hide entries ignored by git"},
+	{0, "group-directories-first", "", "group directories before files; can be augmented with a --sort option, but any use of --sort=none (-U) disables grouping"},
+	{0, "hard-links", "", "after the listing, print entries that are hard links to the same file"},
+	{'h', "human-readable", "", "with -l, print sizes like 1K 234M 2G etc."},
+	{0, "hide", "PATTERN", "do not list implied entries matching shell PATTERN (overridden by -a or -A)"},
+	{'i', "inode", "", "print the index number of each file"},
+	{'I', "ignore", "PATTERN", "do not list implied entries matching shell PATTERN"},
+	{0, "jobs", "N", "read up to N directories at once with -R"},
+	{'l', "", "", "use a long listing format"},
+	{0, "max-depth", "N", "descend at most N levels below each directory with --tree"},
+	{0, "no-config", "", "ignore the config file and MY_LS_OPTIONS"},
+	{'q', "hide-control-chars", "", "print ? instead of nonprintable characters"},
+	{'Q', "quote-name", "", "enclose entry names in double quotes"},
+	{0, "quoting-style", "WORD", "quote entry names with style WORD: " + strings.Join(QuotingStyles, ", ")},
+	{'r', "reverse", "", "reverse order while sorting"},
+	{'R', "recursive", "", "list subdirectories recursively"},
+	{'S', "", "", "sort by file size, largest first"},
+	{0, "sort", "WORD", "sort by WORD instead of name: none (-U), size (-S), time (-t), version (-v), extension (-X)"},
+	{'t', "", "", "sort by time, newest first"},
+	{0, "tree", "", "draw the directory hierarchy as a tree"},
+	{'U', "", "", "do not sort; list entries in directory order"},
+	{'v', "", "", "natural sort of (version) numbers within text"},
+	{'X', "", "", "sort alphabetically by entry extension"},
+	{0, "help", "", "display this help and exit"},
+	{0, "version", "", "output version information and exit"},
+}
+
+// Set at build time with -ldflags "-X github.com/DavJesse/ls-clone/internal/ls.Version=1.2.3"; otherwise
+// the module version is used, as recorded by 'go install'
+var Version = ""
+
+// What is wrong with an option, as held by OptionError.Err
+var (
+	ErrInvalidOption      = errors.New("invalid option")
+	ErrAmbiguousOption    = errors.New("ambiguous option")
+	ErrMissingArgument    = errors.New("option requires an argument")
+	ErrUnexpectedArgument = errors.New("option doesn't allow an argument")
+	ErrInvalidArgument    = errors.New("invalid argument")
+)
+
+// An option on the command line that could not be applied
+// Its message reads as coreutils reports the same mistake, such as "invalid option -- 'm'"
+type OptionError struct {
+	Option  string   // The option as given, such as "-m" or "--colour"; long options are named in full once recognized
+	Value   string   // The argument rejected with ErrInvalidArgument
+	Choices []string // Options an ambiguous prefix matches, or arguments the option accepts
+	Detail  string   // What the option accepts, when it isn't a list of choices
+	Err     error    // One of ErrInvalidOption, ErrAmbiguousOption, ErrMissingArgument, ErrUnexpectedArgument or ErrInvalidArgument
+}
+
+func (e *OptionError) Error() string {
+	short := !strings.HasPrefix(e.Option, "--")
+
+	switch e.Err {
+	case ErrInvalidOption:
+		if short {
+			return "invalid option -- '" + strings.TrimPrefix(e.Option, "-") + "'"
+		}
+		return "unrecognized option '" + e.Option + "'"
+	case ErrAmbiguousOption:
+		return "option '" + e.Option + "' is ambiguous; possibilities: '" + strings.Join(e.Choices, "' '") + "'"
+	case ErrMissingArgument:
+		if short {
+			return "option requires an argument -- '" + strings.TrimPrefix(e.Option, "-") + "'"
+		}
+		return "option '" + e.Option + "' requires an argument"
+	case ErrUnexpectedArgument:
+		return "option '" + e.Option + "' doesn't allow an argument"
+	}
+
+	message := "invalid argument '" + e.Value + "' for '" + e.Option + "'"
+	if len(e.Choices) > 0 {
+		message += "\nvalid arguments are: '" + strings.Join(e.Choices, "', '") + "'"
+	} else if e.Detail != "" {
+		message += "\n" + e.Detail
+	}
+	return message
+}
+
+func (e *OptionError) Unwrap() error {
+	return e.Err
+}
+
+// Finds the short flag 'char' in Flags
+func LookupShortFlag(char rune) (Flag, bool) {
+	for _, flag := range Flags {
+		if flag.Short == char {
+			return flag, true
+		}
+	}
+	return Flag{}, false
+}
+
+// Finds the long options 'name' may stand for: the one it names exactly or, as getopt_long
+// allows, every option it is a prefix of; one match is unambiguous
+func LookupLongOption(name string) []Flag {
+	var matches []Flag
+	for _, flag := range Flags {
+		if flag.Long == "" || !strings.HasPrefix(flag.Long, name) {
+			continue
+		}
+		if flag.Long == name {
+			return []Flag{flag}
+		}
+		matches = append(matches, flag)
+	}
+	return matches
+}
+
+// Writes the --help text, with one line per entry of Flags
+func WriteUsage(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("Usage: my-ls [OPTION]... [FILE]...\n")
+	b.WriteString("List information about the FILEs (the current directory by default).\n")
+	b.WriteString("Sort entries alphabetically if none of -StUvX nor --sort is specified.\n\n")
+	b.WriteString("Mandatory arguments to long options are mandatory for short options too.\n")
+
+	for _, flag := range Flags {
+		// Short flags sit in their own column, as in coreutils: '  -a, --all'
+		usage := "      "
+		if flag.Short != 0 {
+			usage = "  -" + string(flag.Short)
+			if flag.Long != "" {
+				usage += ", "
+			}
+		}
+		if flag.Long != "" {
+			usage += "--" + flag.Long
+			if flag.OptionalArg() {
+				usage += flag.Arg
+			} else if flag.Arg != "" {
+				usage += "=" + flag.Arg
+			}
+		} else if flag.Arg != "" {
+			usage += " " + flag.Arg
+		}
+
+		// Descriptions start in column 30; longer usages push theirs onto the next line
+		if len(usage) < 29 {
+			fmt.Fprintf(&b, "%-29s%s\n", usage, flag.Help)
+		} else {
+			fmt.Fprintf(&b, "%s\n%29s%s\n", usage, "", flag.Help)
+		}
+	}
+
+	b.WriteString("\nExit status:\n")
+	b.WriteString(" 0  if OK,\n")
+	b.WriteString(" 1  if output failed,\n")
+	b.WriteString(" 2  if the command line was invalid or a file could not be listed.\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// Writes the --version text
+func WriteVersion(w io.Writer) error {
+	version := Version
+	if version == "" {
+		version = "devel"
+		if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+			version = info.Main.Version
+		}
+	}
+	_, err := fmt.Fprintf(w, "my-ls %s\n", version)
+	return err
+}
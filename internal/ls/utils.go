@@ -1,71 +1,116 @@
 package internal
 
+import (
+	"io/fs"
+	"os"
+	"time"
+)
+
+// A listed entry, holding only what locates it and its metadata
+// Display strings are formatted from Meta when the entry is rendered, so large listings stay small
 type FileInfo struct {
+	Name          string // Base name, or the operand as given
+	Path          string // Where the entry is read from, in the listing's filesystem
+	Dir           string // Directory the entry was read from
+	RecursiveList []FileInfo
+	Meta          MetaData
+}
+
+// An entry as RetrieveFileInfo returns it, with its display strings formatted up front
+// Kept for callers of the original API; listings use the compact FileInfo
+type LegacyFileInfo struct {
 	Index         string
 	DocName       string
 	DocPerm       string
-	RecursiveList []FileInfo
+	RecursiveList []LegacyFileInfo
 	PlusHidden    string
 	ReverseList   string
 	ModTime       string
+	Entry         FileInfo // The entry the strings were formatted from
 }
 
-type ReverseAlpha []FileInfo
-type Alphabetic []FileInfo
-type ByTime []FileInfo
-
 type MetaData struct {
 	HardLinkCount int
 	UserID        string
 	GroupID       string
+	UID           uint32
+	GID           uint32
+	Inode         uint64
+	Device        uint64
+	Rdev          uint64 // Device number, for character and block devices
+	Mode          os.FileMode
+	Size          int64
+	Blocks        int64 // 512-byte blocks, as reported by stat
+	AccessTime    time.Time
+	ModTime       time.Time
+	ChangeTime    time.Time
+	LinkTarget    string // Only set for symbolic links
 }
 
-type DirFile struct {
-	Dir   string
-	Files []string
+// Identifies a file uniquely across mounted filesystems
+// Entries sharing a DevIno are hard links to the same file
+type DevIno struct {
+	Device uint64
+	Inode  uint64
 }
 
-// Give sort.Sort interface size for sorting
-func (f Alphabetic) Len() int {
-	return len(f)
-}
+// Listing behaviour selected by the user's flags
+type Options struct {
+	Long                  bool     // -l
+	Recursive             bool     // -R
+	Jobs                  int      // --jobs=N, directories read at once during -R; zero uses one per CPU
+	All                   bool     // -a
+	AlmostAll             bool     // -A
+	Directory             bool     // -d, lists directory operands themselves
+	Reverse               bool     // -r
+	Unsorted              bool     // -U, --sort=none, lists entries in directory order, streaming them when possible
+	NoColor               bool     // Prints names without color codes, as -f and --color=never ask
+	ColorAuto             bool     // --color=auto, colors names only when writing to a terminal
+	HumanReadable         bool     // -h, shows -l sizes in K, M, G and so on
+	Sort                  string   // -S, -t, -v, -X, --sort=WORD; empty sorts by name
+	GroupDirectoriesFirst bool     // --group-directories-first, lists directories and links to them ahead of files
+	Inode                 bool     // -i
+	HardLinks             bool     // --hard-links, reports entries sharing an inode
+	Format                string   // --format=WORD, empty for the default listing
+	Fields                []string // --fields=LIST, columns for csv and tsv
+	MaxDepth              int      // --max-depth=N, levels shown by the tree view; zero is unlimited
 
-// Give sorting algoriths parameter for sorting
-func (f Alphabetic) Less(i, j int) bool {
-	return f[i].Index < f[j].Index
-}
+	QuotingStyle string // --quoting-style=WORD, -b, -Q; empty prints names literally
+	HideControl  bool   // -q
 
-// Handle swapping
-func (f Alphabetic) Swap(i, j int) {
-	f[i], f[j] = f[j], f[i]
-}
+	Hide          HideFunc // Decides hidden entries, ignored with -a and -A; nil hides dotfiles
+	HidePatterns  []string // --hide=PATTERN, ignored with -a and -A
+	Ignore        []string // -I, --ignore=PATTERN, applied even with -a and -A
+	IgnoreBackups bool     // -B, ignores names ending in '~'
 
-// Give sort.Sort interface size for sorting
-func (f ReverseAlpha) Len() int {
-	return len(f)
-}
+	FS       fs.FS    // Filesystem to list; nil lists the operating system's
+	Archives []string // --archive=FILE[:DIR], archives listed as directories
 
-// Give sorting algoriths parameter for sorting
-func (f ReverseAlpha) Less(i, j int) bool {
-	return f[i].Index > f[j].Index
+	Help     bool // --help, prints usage instead of listing
+	Version  bool // --version, prints the version instead of listing
+	NoConfig bool // --no-config, ignores the config file and MY_LS_OPTIONS
 }
 
-// Handle swapping
-func (f ReverseAlpha) Swap(i, j int) {
-	f[i], f[j] = f[j], f[i]
+// Decides whether an entry of directory 'dir' is left out of a listing
+type HideFunc func(dir, name string, isDir bool) bool
+
+type DirFile struct {
+	Dir   string
+	Files []string
 }
 
-// Give sort.Sort interface size for sorting
-func (f ByTime) Len() int {
-	return len(f)
+// A command-line operand that could not be listed
+// Reads like ls does: cannot access 'x': No such file or directory
+type OperandError struct {
+	Op   string // What failed: "access" or "open directory"
+	Path string // The operand as given
+	Err  error
 }
 
-// Give sorting algoriths parameter for sorting
-func (f ByTime) Less(i, j int) bool {
-	return f[i].ModTime > f[j].ModTime
+func (e *OperandError) Error() string {
+	return "cannot " + e.Op + " " + QuoteName(e.Path, "shell-escape-always", false) + ": " + Strerror(e.Err)
 }
 
-// Handle swapping
-func (f ByTime) Swap(i, j int) {
-	f[i], f[j] = f[j], f[i]
+func (e *OperandError) Unwrap() error {
+	return e.Err
 }
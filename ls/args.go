@@ -0,0 +1,49 @@
+package ls
+
+import (
+	internal "github.com/DavJesse/ls-clone/internal/ls"
+)
+
+// OptionError reports an option on a command line passed to ParseArgs that
+// could not be applied. Its message reads like ls: invalid option -- 'm'
+type OptionError = internal.OptionError
+
+// What is wrong with an option, as held by OptionError.Err; test with errors.Is.
+var (
+	ErrInvalidOption      = internal.ErrInvalidOption
+	ErrAmbiguousOption    = internal.ErrAmbiguousOption
+	ErrMissingArgument    = internal.ErrMissingArgument
+	ErrUnexpectedArgument = internal.ErrUnexpectedArgument
+	ErrInvalidArgument    = internal.ErrInvalidArgument
+)
+
+// ParseArgs reads a my-ls command line, without the program name, into Options
+// and the paths to list, which default to ".".
+//
+// Every option my-ls accepts is accepted, but only those that choose what is
+// listed and in what order are kept; output options such as -l or --format are
+// for Render to decide. Mistakes are returned as an *OptionError.
+func ParseArgs(args []string) (Options, []string, error) {
+	opts, paths, err := internal.ParseArgs(args)
+	if err != nil {
+		return Options{}, nil, err
+	}
+
+	result := Options{
+		All:                   opts.All,
+		AlmostAll:             opts.AlmostAll,
+		Recursive:             opts.Recursive,
+		Directory:             opts.Directory,
+		Sort:                  Sort(opts.Sort),
+		Reverse:               opts.Reverse,
+		GroupDirectoriesFirst: opts.GroupDirectoriesFirst,
+		Ignore:                opts.Ignore,
+		Hide:                  opts.HidePatterns,
+		IgnoreBackups:         opts.IgnoreBackups,
+		HideFunc:              opts.Hide,
+	}
+	if opts.Unsorted {
+		result.Sort = SortNone
+	}
+	return result, paths, nil
+}
@@ -0,0 +1,27 @@
+// Package ls is the listing engine behind my-ls, for use from other Go programs.
+//
+// List reads files and directories the way the my-ls command does, honouring
+// hidden files, ignore patterns and sort orders, and returns typed entries.
+// Walk streams a single directory entry by entry, and Render writes entries
+// in any of the command's output formats:
+//
+//	entries, err := ls.List(ctx, []string{"/var/log"}, ls.Options{Sort: ls.SortTime})
+//	if err != nil {
+//		// Entries that could be read are still returned
+//	}
+//	ls.Render(os.Stdout, entries, ls.FormatLong)
+//
+// ParseArgs reads a my-ls command line into Options, reporting mistakes as an
+// *OptionError that can be inspected with errors.As.
+//
+// # Compatibility
+//
+// This package follows semantic versioning. Within a major version, exported
+// identifiers are not removed or renamed, the meaning of existing Options and
+// Entry fields does not change, and the output of each Format stays the same
+// apart from bug fixes that bring it closer to GNU ls. New fields, formats and
+// sort orders may be added in minor versions, so build Options and Entry
+// values with field names rather than positional literals.
+//
+// Everything under internal/ may change at any time and is not covered.
+package ls
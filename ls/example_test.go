@@ -0,0 +1,90 @@
+package ls_test
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/DavJesse/ls-clone/ls"
+)
+
+// Builds a small directory for the examples to list
+func exampleDir() string {
+	dir, _ := os.MkdirTemp("", "ls-example")
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("hello"), 0o644)
+	os.WriteFile(filepath.Join(dir, ".profile"), nil, 0o644)
+	os.Mkdir(filepath.Join(dir, "docs"), 0o755)
+	os.WriteFile(filepath.Join(dir, "docs", "guide.md"), []byte("# Guide"), 0o644)
+	return dir
+}
+
+func ExampleList() {
+	dir := exampleDir()
+	defer os.RemoveAll(dir)
+
+	entries, err := ls.List(context.Background(), []string{dir}, ls.Options{})
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			fmt.Println(entry.Name, "directory")
+		} else {
+			fmt.Println(entry.Name, entry.Size, "bytes")
+		}
+	}
+	// Output:
+	// docs directory
+	// notes.txt 5 bytes
+}
+
+func ExampleList_recursive() {
+	dir := exampleDir()
+	defer os.RemoveAll(dir)
+
+	entries, _ := ls.List(context.Background(), []string{dir}, ls.Options{Recursive: true, Sort: ls.SortSize, Reverse: true})
+	for _, entry := range entries {
+		fmt.Println(entry.Name)
+		for _, child := range entry.Children {
+			fmt.Println("  " + child.Name)
+		}
+	}
+	// Output:
+	// notes.txt
+	// docs
+	//   guide.md
+}
+
+func ExampleWalk() {
+	dir := exampleDir()
+	defer os.RemoveAll(dir)
+
+	count := 0
+	ls.Walk(context.Background(), dir, ls.Options{AlmostAll: true}, func(entry ls.Entry) error {
+		count++
+		return nil
+	})
+	fmt.Println(count, "entries")
+	// Output:
+	// 3 entries
+}
+
+func ExampleRender() {
+	modified := time.Date(2020, time.January, 2, 15, 4, 0, 0, time.Local)
+	entries := []ls.Entry{
+		{Name: "bin", Mode: fs.ModeDir | 0o755, Size: 4096, Links: 2, Owner: "root", Group: "root", ModTime: modified},
+		{Name: "notes.txt", Mode: 0o644, Size: 5, Links: 1, Owner: "alice", Group: "staff", ModTime: modified},
+	}
+
+	ls.Render(os.Stdout, entries, ls.FormatShort)
+	ls.Render(os.Stdout, entries, ls.FormatLong)
+	// Output:
+	// bin/
+	// notes.txt
+	// drwxr-xr-x 2 root  root  4096 Jan  2  2020 bin/
+	// -rw-r--r-- 1 alice staff    5 Jan  2  2020 notes.txt
+}
@@ -0,0 +1,292 @@
+package ls
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"path"
+	"slices"
+	"time"
+
+	internal "github.com/DavJesse/ls-clone/internal/ls"
+)
+
+// Sort is the order of a listing, as named by my-ls --sort=WORD.
+type Sort string
+
+const (
+	SortName      Sort = "name" // Alphabetical, ignoring case; the default
+	SortNone      Sort = "none" // Directory order, as the filesystem returns it
+	SortSize      Sort = "size" // Largest first
+	SortTime      Sort = "time" // Most recently modified first
+	SortVersion   Sort = "version"
+	SortExtension Sort = "extension"
+)
+
+// Options selects what List reads and how it orders it.
+// The zero value lists like my-ls with no flags.
+type Options struct {
+	All       bool // Include dotfiles, '.' and '..' (-a)
+	AlmostAll bool // Include dotfiles, but not '.' and '..' (-A)
+	Recursive bool // Read subdirectories into Entry.Children (-R)
+	Directory bool // Return directory operands themselves, not their contents (-d)
+	Jobs      int  // Directories read at once with Recursive; zero reads one per CPU (--jobs)
+
+	Sort    Sort // Empty sorts by name
+	Reverse bool // Reverse the sort; has no effect with SortNone (-r)
+
+	// List directories, and symbolic links to them, before other entries;
+	// has no effect with SortNone (--group-directories-first)
+	GroupDirectoriesFirst bool
+
+	Ignore        []string // Glob patterns left out even with All or AlmostAll (-I)
+	Hide          []string // Glob patterns left out unless All or AlmostAll is set (--hide)
+	IgnoreBackups bool     // Leave out names ending in '~' (-B)
+	GitIgnore     bool     // Hide entries git would ignore (--gitignore)
+
+	// Decides hidden entries in place of the dotfile rule; ignored with All or AlmostAll
+	HideFunc func(dir, name string, isDir bool) bool
+
+	// Filesystem to list, such as an fstest.MapFS or embed.FS; nil lists the
+	// operating system's. Paths are then io/fs names, with "." for the root.
+	// Owners, link counts, inodes and link targets are only known if it
+	// implements MetaFS; otherwise owner and group read "?".
+	FS fs.FS
+}
+
+// MetaFS is an fs.FS that can describe files as fully as ls -l shows them.
+type MetaFS interface {
+	fs.FS
+
+	// Lstat describes name itself, not the file a symbolic link points to.
+	// Only the metadata fields of the Entry are used; Name, Path, Dir and
+	// Children are ignored.
+	Lstat(name string) (Entry, error)
+}
+
+// Entry is a listed file and its metadata.
+type Entry struct {
+	Name string // As shown by ls: the base name, or the operand as given
+	Path string
+	Dir  string // Directory the entry was read from
+
+	Mode   fs.FileMode
+	Size   int64
+	Blocks int64 // 512-byte blocks, as reported by stat
+	Links  int
+
+	Owner string // User name, or the numeric ID if it has none
+	Group string
+	UID   uint32
+	GID   uint32
+
+	Inode  uint64
+	Device uint64
+	Rdev   uint64 // Device number, for character and block devices
+
+	AccessTime time.Time
+	ModTime    time.Time
+	ChangeTime time.Time
+
+	LinkTarget string // Only set for symbolic links
+
+	// Contents of a directory read with Options.Recursive; nil for entries that were not read
+	Children []Entry
+}
+
+// IsDir reports whether the entry is a directory.
+func (e Entry) IsDir() bool {
+	return e.Mode.IsDir()
+}
+
+// List reads each path like my-ls does.
+//
+// Paths that are not directories, and every path when Options.Directory is set,
+// are returned as entries themselves, first and in sorted order. Directories
+// follow, each replaced by its sorted contents; Entry.Dir tells which directory
+// an entry came from. An empty path list reads the current directory.
+//
+// Paths that can't be read, and with Options.Recursive subdirectories that
+// can't be read, are reported together in the returned error, which wraps an
+// *OperandError naming each; everything else is still returned, and a
+// subdirectory that could not be read has nil Children. List stops early,
+// returning ctx.Err(), if ctx is cancelled.
+func List(ctx context.Context, paths []string, opts Options) ([]Entry, error) {
+	internalOpts, err := opts.internal()
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+
+	files, dirs, errs := internal.SplitOperands(paths, internalOpts)
+	entries := newEntries(files, false)
+
+	for _, dir := range dirs {
+		if err := ctx.Err(); err != nil {
+			return entries, err
+		}
+
+		// Subdirectories are read by the same worker pool as my-ls -R
+		contents, dirErrs := internal.RetrieveEntriesContext(ctx, dir, internalOpts)
+		if err := ctx.Err(); err != nil {
+			return entries, err
+		}
+		errs = append(errs, dirErrs...)
+		entries = append(entries, newEntries(contents, opts.Recursive)...)
+	}
+	return entries, errors.Join(errs...)
+}
+
+// Walk reads a single directory and calls fn for each entry as soon as it is read,
+// in directory order, so memory use stays flat for huge directories.
+// Sort and Recursive are not applied. Walk stops at the first error from fn,
+// or when ctx is cancelled, and returns it.
+func Walk(ctx context.Context, dir string, opts Options, fn func(Entry) error) error {
+	internalOpts, err := opts.internal()
+	if err != nil {
+		return err
+	}
+
+	return internal.StreamDirectory(dir, internalOpts, func(file internal.FileInfo) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		return fn(newEntry(file, false))
+	})
+}
+
+// OperandError reports a path passed to List that could not be read.
+// Its message reads like ls: cannot access 'x': No such file or directory
+type OperandError = internal.OperandError
+
+// Checks the options and translates them for the listing engine
+func (opts Options) internal() (internal.Options, error) {
+	result := internal.Options{
+		All:                   opts.All,
+		AlmostAll:             opts.AlmostAll,
+		Recursive:             opts.Recursive,
+		Jobs:                  opts.Jobs,
+		Directory:             opts.Directory,
+		Reverse:               opts.Reverse,
+		GroupDirectoriesFirst: opts.GroupDirectoriesFirst,
+		Ignore:                opts.Ignore,
+		HidePatterns:          opts.Hide,
+		IgnoreBackups:         opts.IgnoreBackups,
+		Hide:                  opts.HideFunc,
+		FS:                    opts.FS,
+	}
+	if metaFS, ok := opts.FS.(MetaFS); ok {
+		result.FS = metaAdapter{metaFS}
+	}
+	if opts.GitIgnore {
+		result.Hide = internal.NewGitIgnoreMatcher().Hide
+	}
+
+	if opts.Sort != "" {
+		if !slices.Contains(internal.SortModes, string(opts.Sort)) {
+			return result, errors.New("ls: unknown sort order '" + string(opts.Sort) + "'")
+		}
+		internal.SetSort(string(opts.Sort), &result)
+	}
+
+	for _, pattern := range append(slices.Clone(opts.Ignore), opts.Hide...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return result, errors.New("ls: invalid pattern '" + pattern + "': " + err.Error())
+		}
+	}
+	return result, nil
+}
+
+// Copies listing entries into the public form
+// Directories read by a recursive listing get a non-nil Children, even if empty
+func newEntries(files []internal.FileInfo, recursive bool) []Entry {
+	entries := make([]Entry, 0, len(files))
+	for i := range files {
+		entries = append(entries, newEntry(files[i], recursive))
+	}
+	return entries
+}
+
+func newEntry(file internal.FileInfo, recursive bool) Entry {
+	meta := file.Meta
+	entry := Entry{
+		Name:       file.Name,
+		Path:       file.Path,
+		Dir:        file.Dir,
+		Mode:       meta.Mode,
+		Size:       meta.Size,
+		Blocks:     meta.Blocks,
+		Links:      meta.HardLinkCount,
+		Owner:      meta.UserID,
+		Group:      meta.GroupID,
+		UID:        meta.UID,
+		GID:        meta.GID,
+		Inode:      meta.Inode,
+		Device:     meta.Device,
+		Rdev:       meta.Rdev,
+		AccessTime: meta.AccessTime,
+		ModTime:    meta.ModTime,
+		ChangeTime: meta.ChangeTime,
+		LinkTarget: meta.LinkTarget,
+	}
+
+	if recursive && file.RecursiveList != nil {
+		entry.Children = newEntries(file.RecursiveList, true)
+	}
+	return entry
+}
+
+// Copies public entries back for the renderers
+func fileInfos(entries []Entry) []internal.FileInfo {
+	files := make([]internal.FileInfo, 0, len(entries))
+	for i := range entries {
+		file := internal.NewEntry(entries[i].Dir, entries[i].Name, metaData(entries[i]))
+		file.Path = entries[i].Path
+		file.RecursiveList = fileInfos(entries[i].Children)
+		files = append(files, file)
+	}
+	return files
+}
+
+func metaData(e Entry) internal.MetaData {
+	return internal.MetaData{
+		HardLinkCount: e.Links,
+		UserID:        e.Owner,
+		GroupID:       e.Group,
+		UID:           e.UID,
+		GID:           e.GID,
+		Inode:         e.Inode,
+		Device:        e.Device,
+		Rdev:          e.Rdev,
+		Mode:          e.Mode,
+		Size:          e.Size,
+		Blocks:        e.Blocks,
+		AccessTime:    e.AccessTime,
+		ModTime:       e.ModTime,
+		ChangeTime:    e.ChangeTime,
+		LinkTarget:    e.LinkTarget,
+	}
+}
+
+// Lets a public MetaFS serve the listing engine
+type metaAdapter struct {
+	fsys MetaFS
+}
+
+func (a metaAdapter) Open(name string) (fs.File, error) {
+	return a.fsys.Open(name)
+}
+
+func (a metaAdapter) Stat(name string) (fs.FileInfo, error) {
+	return fs.Stat(a.fsys, name)
+}
+
+func (a metaAdapter) Lstat(name string) (internal.MetaData, error) {
+	entry, err := a.fsys.Lstat(name)
+	if err != nil {
+		return internal.MetaData{}, err
+	}
+	return metaData(entry), nil
+}
@@ -0,0 +1,96 @@
+package ls
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+
+	internal "github.com/DavJesse/ls-clone/internal/ls"
+)
+
+// Format is an output format accepted by Render.
+type Format string
+
+const (
+	FormatShort  Format = "short"  // One name per line, as my-ls prints by default
+	FormatLong   Format = "long"   // Aligned details, as my-ls -l prints
+	FormatJSON   Format = "json"   // An indented JSON array, as --format=json prints
+	FormatNDJSON Format = "ndjson" // One JSON object per line
+	FormatCSV    Format = "csv"    // RFC 4180 values with a header row
+	FormatTSV    Format = "tsv"    // Tab-separated values with a header row
+)
+
+// Render writes entries to w in the given format.
+//
+// Entries are written as one listing, in the order given. With FormatShort and
+// FormatLong, each directory with Children is then written as its own section,
+// headed by its path, as ls -R does; the other formats list children right
+// after their directory. Names are written without color codes or quoting, as
+// ls does when output is not a terminal, and FormatShort and FormatLong follow
+// each with its type indicator, as ls -F does: '/' for directories, '@' for
+// symbolic links, '*' for executables, '|' for FIFOs and '=' for sockets.
+//
+// Output is buffered and written to w once per directory section, so w
+// need not be buffered itself.
+func Render(w io.Writer, entries []Entry, format Format) error {
+	files := fileInfos(entries)
+	opts := internal.Options{NoColor: true, Recursive: hasChildren(entries)}
+	out := bufio.NewWriter(w)
+
+	var err error
+	switch format {
+	case FormatShort, FormatLong:
+		opts.Long = format == FormatLong
+		if err = internal.RenderEntries(out, files, opts); err == nil {
+			err = renderSections(out, entries, files, opts)
+		}
+	case FormatJSON:
+		err = internal.RenderJSON(out, files, opts)
+	case FormatNDJSON:
+		err = internal.RenderNDJSON(out, files, opts)
+	case FormatCSV:
+		err = internal.RenderCSV(out, files, opts)
+	case FormatTSV:
+		err = internal.RenderTSV(out, files, opts)
+	default:
+		return errors.New("ls: unknown format '" + string(format) + "'")
+	}
+
+	if err != nil {
+		return err
+	}
+	return out.Flush()
+}
+
+// Writes a section for each directory that was read, then for its own subdirectories
+func renderSections(w io.Writer, entries []Entry, files []internal.FileInfo, opts internal.Options) error {
+	for i := range entries {
+		if entries[i].Children == nil {
+			continue
+		}
+		if _, err := fmt.Fprintln(w); err != nil {
+			return err
+		}
+
+		sectionOpts := opts
+		sectionOpts.Recursive = false
+		if err := internal.RenderDirectory(w, entries[i].Path, files[i].RecursiveList, sectionOpts, true); err != nil {
+			return err
+		}
+		if err := renderSections(w, entries[i].Children, files[i].RecursiveList, opts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Reports whether any entry carries the contents of a directory
+func hasChildren(entries []Entry) bool {
+	for i := range entries {
+		if entries[i].Children != nil {
+			return true
+		}
+	}
+	return false
+}
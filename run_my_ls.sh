@@ -1,2 +1,2 @@
 #!/bin/bash
-go run cmd/my-ls/main.go "$1" "$2"
\ No newline at end of file
+go run cmd/my-ls/main.go "$@"
\ No newline at end of file
@@ -0,0 +1,212 @@
+package tests
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	internal "github.com/DavJesse/ls-clone/internal/ls"
+)
+
+// Members written into every archive fixture; 'bin' is only implied by its members
+var archiveMembers = []struct {
+	name   string
+	mode   fs.FileMode
+	size   int
+	target string
+	age    time.Duration
+}{
+	{"bin/tool", 0o755, 5, "", 2 * time.Hour},
+	{"bin/link", fs.ModeSymlink | 0o777, 0, "../lib/big.so", time.Hour},
+	{"lib/", fs.ModeDir | 0o755, 0, "", 0},
+	{"lib/big.so", 0o644, 3000, "", 3 * time.Hour},
+	{"lib/small.so", 0o644, 10, "", 0},
+}
+
+var archiveTime = time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+// Writes the fixture members as a tar stream
+func writeTar(t *testing.T, w io.Writer) {
+	writer := tar.NewWriter(w)
+	for _, m := range archiveMembers {
+		header := &tar.Header{Name: m.name, Mode: int64(m.mode.Perm()), Size: int64(m.size), ModTime: archiveTime.Add(-m.age), Uname: "alice", Gname: "staff", Uid: 1000, Gid: 50}
+		switch {
+		case m.mode.IsDir():
+			header.Typeflag = tar.TypeDir
+		case m.mode&fs.ModeSymlink != 0:
+			header.Typeflag, header.Linkname = tar.TypeSymlink, m.target
+		default:
+			header.Typeflag = tar.TypeReg
+		}
+		if err := writer.WriteHeader(header); err != nil {
+			t.Fatalf("Failed to write tar header: %v", err)
+		}
+		if _, err := writer.Write(make([]byte, m.size)); err != nil {
+			t.Fatalf("Failed to write tar member: %v", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Failed to close tar: %v", err)
+	}
+}
+
+// Builds the same archive as .tar, .tar.gz and .zip, returning their paths
+func makeArchives(t *testing.T) (string, string, string) {
+	tempDir := t.TempDir()
+
+	var tarData bytes.Buffer
+	writeTar(t, &tarData)
+	tarPath := filepath.Join(tempDir, "release.tar")
+	if err := os.WriteFile(tarPath, tarData.Bytes(), 0o644); err != nil {
+		t.Fatalf("Failed to write tar: %v", err)
+	}
+
+	var gzData bytes.Buffer
+	gz := gzip.NewWriter(&gzData)
+	writeTar(t, gz)
+	gz.Close()
+	gzPath := filepath.Join(tempDir, "release.tar.gz")
+	if err := os.WriteFile(gzPath, gzData.Bytes(), 0o644); err != nil {
+		t.Fatalf("Failed to write tar.gz: %v", err)
+	}
+
+	var zipData bytes.Buffer
+	writer := zip.NewWriter(&zipData)
+	for _, m := range archiveMembers {
+		header := &zip.FileHeader{Name: m.name, Modified: archiveTime.Add(-m.age)}
+		header.SetMode(m.mode)
+		member, err := writer.CreateHeader(header)
+		if err != nil {
+			t.Fatalf("Failed to write zip header: %v", err)
+		}
+		if m.target != "" {
+			member.Write([]byte(m.target))
+		} else if !m.mode.IsDir() {
+			member.Write(make([]byte, m.size))
+		}
+	}
+	writer.Close()
+	zipPath := filepath.Join(tempDir, "release.zip")
+	if err := os.WriteFile(zipPath, zipData.Bytes(), 0o644); err != nil {
+		t.Fatalf("Failed to write zip: %v", err)
+	}
+
+	return tarPath, gzPath, zipPath
+}
+
+// Test every archive format lists the same members, recursively, with paths naming the archive
+func TestRetrieveArchive_Formats(t *testing.T) {
+	tarPath, gzPath, zipPath := makeArchives(t)
+
+	for _, file := range []string{tarPath, gzPath, zipPath} {
+		files, isDir, err := internal.RetrieveArchive(internal.ArchiveOperand{Spec: file, File: file, Dir: "."}, internal.Options{Recursive: true})
+		if err != nil || !isDir {
+			t.Fatalf("RetrieveArchive(%v) failed: %v", file, err)
+		}
+
+		result := strings.ReplaceAll(fmt.Sprint(mapFSPaths(files)), file, "A")
+		expect := "[A:/bin A:/bin/link A:/bin/tool A:/lib A:/lib/big.so A:/lib/small.so]"
+		if result != expect {
+			t.Errorf("%v: Expected %v, Got %v", filepath.Base(file), expect, result)
+		}
+
+		link := files[0].RecursiveList[0]
+		if link.Meta.LinkTarget != "../lib/big.so" || link.Meta.Mode&fs.ModeSymlink == 0 {
+			t.Errorf("%v: Expected link to ../lib/big.so, Got %+v", filepath.Base(file), link.Meta)
+		}
+	}
+}
+
+// Test members keep their metadata and sort with -S, -t and -r
+func TestRetrieveArchive_Sorting(t *testing.T) {
+	_, gzPath, _ := makeArchives(t)
+
+	testCases := []struct {
+		flag   string
+		expect string
+	}{
+		{"-S", "[big.so small.so]"},
+		{"-Sr", "[small.so big.so]"},
+		{"-t", "[small.so big.so]"},
+	}
+
+	for _, tc := range testCases {
+		files, _, err := internal.RetrieveArchive(internal.ParseArchiveSpec(gzPath+":/lib"), internal.ParseFlag(tc.flag))
+		if err != nil {
+			t.Fatalf("RetrieveArchive failed: %v", err)
+		}
+		if result := fmt.Sprint(entryNames(files)); result != tc.expect {
+			t.Errorf("RetrieveArchive(%v) = %v; want %v", tc.flag, result, tc.expect)
+		}
+	}
+
+	files, _, _ := internal.RetrieveArchive(internal.ParseArchiveSpec(gzPath+":/lib/big.so"), internal.ParseFlag("-l"))
+	var buf bytes.Buffer
+	if err := internal.RenderLong(&buf, files, internal.Options{Long: true, NoColor: true}); err != nil {
+		t.Fatalf("RenderLong failed: %v", err)
+	}
+	expect := "-rw-r--r-- 1 alice staff 3000 Mar  1  2024 " + gzPath + ":/lib/big.so\n"
+	if buf.String() != expect {
+		t.Errorf("Expected %q, Got %q", expect, buf.String())
+	}
+}
+
+// Test archive operands are recognised by name, and 'FILE:DIR' only splits on archives
+func TestSplitArchives(t *testing.T) {
+	tarPath, _, zipPath := makeArchives(t)
+	plainFile := filepath.Join(filepath.Dir(tarPath), "notes.txt")
+	if err := os.WriteFile(plainFile, nil, 0o644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	plain, archives := internal.SplitArchives([]string{tarPath, plainFile, zipPath + ":/bin/", "x:y"}, internal.Options{})
+	if fmt.Sprint(plain) != fmt.Sprint([]string{plainFile, "x:y"}) {
+		t.Errorf("Unexpected plain operands: %v", plain)
+	}
+	if len(archives) != 2 || archives[0].Dir != "." || archives[1].File != zipPath || archives[1].Dir != "bin" {
+		t.Errorf("Unexpected archive operands: %+v", archives)
+	}
+
+	if plain, archives = internal.SplitArchives([]string{tarPath}, internal.ParseFlag("-d")); len(plain) != 1 || len(archives) != 0 {
+		t.Errorf("Expected -d to list the archive itself, Got %v, %+v", plain, archives)
+	}
+}
+
+// Test missing archives and members are reported as ls reports missing files
+func TestRetrieveArchive_Errors(t *testing.T) {
+	tarPath, _, _ := makeArchives(t)
+
+	_, _, err := internal.RetrieveArchive(internal.ParseArchiveSpec(tarPath+":/nope"), internal.Options{})
+	var operandErr *internal.OperandError
+	if !errors.As(err, &operandErr) || err.Error() != "cannot access '"+tarPath+":/nope': No such file or directory" {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	notArchive := filepath.Join(filepath.Dir(tarPath), "fake.zip")
+	os.WriteFile(notArchive, []byte("not a zip"), 0o644)
+	if _, _, err := internal.RetrieveArchive(internal.ParseArchiveSpec(notArchive), internal.Options{}); err == nil {
+		t.Errorf("Expected error for a file that is not an archive, Got nil")
+	}
+}
+
+// Test --archive takes the place of the default '.' operand
+func TestParseArgs_Archive(t *testing.T) {
+	opts, paths, err := internal.ParseArgs([]string{"-l", "--archive=release.tar.gz:/bin"})
+	if err != nil || len(paths) != 0 || fmt.Sprint(opts.Archives) != "[release.tar.gz:/bin]" {
+		t.Errorf("Unexpected result: %v, %v, %v", opts.Archives, paths, err)
+	}
+
+	if operand := internal.ParseArchiveOption("artifact.bin:/usr/lib"); operand.File != "artifact.bin" || operand.Dir != "usr/lib" {
+		t.Errorf("Unexpected operand: %+v", operand)
+	}
+}
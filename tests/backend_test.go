@@ -0,0 +1,181 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	internal "github.com/DavJesse/ls-clone/internal/ls"
+	"github.com/DavJesse/ls-clone/ls"
+)
+
+// Builds an in-memory tree to list
+func makeMapFS() fstest.MapFS {
+	modified := time.Date(2020, time.January, 2, 15, 4, 0, 0, time.UTC)
+	return fstest.MapFS{
+		"README.md":        {Data: []byte("# readme"), Mode: 0o644, ModTime: modified},
+		"bin/tool":         {Data: []byte("#!/bin/sh\n"), Mode: 0o755, ModTime: modified.Add(time.Hour)},
+		"bin/large.dat":    {Data: make([]byte, 2048), Mode: 0o644, ModTime: modified},
+		"docs/.hidden":     {Mode: 0o644, ModTime: modified},
+		"docs/guide/a.txt": {Data: []byte("a"), Mode: 0o600, ModTime: modified},
+	}
+}
+
+// Collects the paths of a recursive listing, depth first
+func mapFSPaths(files []internal.FileInfo) []string {
+	var paths []string
+	for i := range files {
+		paths = append(paths, files[i].Path)
+		paths = append(paths, mapFSPaths(files[i].RecursiveList)...)
+	}
+	return paths
+}
+
+// Test an fs.FS is walked and sorted like the operating system's filesystem
+func TestRetrieveEntries_MapFS(t *testing.T) {
+	opts := internal.Options{FS: makeMapFS(), Recursive: true}
+
+	result := fmt.Sprint(mapFSPaths(retrieveEntries(t, ".", opts)))
+	expect := "[bin bin/large.dat bin/tool docs docs/guide docs/guide/a.txt README.md]"
+	if result != expect {
+		t.Errorf("Expected %v, Got %v", expect, result)
+	}
+
+	opts = internal.Options{FS: makeMapFS(), All: true, Sort: "size"}
+	result = fmt.Sprint(entryNames(retrieveEntries(t, "bin", opts)))
+	if result != "[large.dat tool . ..]" {
+		t.Errorf("Expected [large.dat tool . ..], Got %v", result)
+	}
+}
+
+// Test operands that don't exist in an fs.FS are reported as ls reports them
+func TestSplitOperands_MapFS(t *testing.T) {
+	files, dirs, errs := internal.SplitOperands([]string{"docs", "nope", "README.md"}, internal.Options{FS: makeMapFS()})
+
+	if len(files) != 1 || files[0].Name != "README.md" || fmt.Sprint(dirs) != "[docs]" {
+		t.Errorf("Unexpected split: %v, %v", entryNames(files), dirs)
+	}
+	if len(errs) != 1 || errs[0].Error() != "cannot access 'nope': No such file or directory" {
+		t.Errorf("Unexpected errors: %v", errs)
+	}
+}
+
+// Test backends without owner data show '?' in long listings
+func TestRenderLong_MapFS(t *testing.T) {
+	opts := internal.Options{FS: makeMapFS(), Long: true, NoColor: true}
+
+	var buf bytes.Buffer
+	if err := internal.RenderLong(&buf, retrieveEntries(t, "docs/guide", opts), opts); err != nil {
+		t.Fatalf("RenderLong failed: %v", err)
+	}
+
+	expect := "-rw------- 1 ? ? 1 Jan  2  2020 a.txt\n"
+	if buf.String() != expect {
+		t.Errorf("Expected %q, Got %q", expect, buf.String())
+	}
+}
+
+// Reads link targets from an fstest.MapFS, where a link's data is its target
+type linkFS struct {
+	fstest.MapFS
+}
+
+func (l linkFS) Lstat(name string) (internal.MetaData, error) {
+	file, ok := l.MapFS[name]
+	if !ok {
+		return internal.MetaData{}, &fs.PathError{Op: "lstat", Path: name, Err: fs.ErrNotExist}
+	}
+	meta := internal.MetaData{Mode: file.Mode, Size: int64(len(file.Data)), UserID: "?", GroupID: "?", HardLinkCount: 1}
+	if file.Mode&fs.ModeSymlink != 0 {
+		meta.LinkTarget = string(file.Data)
+	}
+	return meta, nil
+}
+
+// Test link targets are looked up in the backend, never on disk, and unknown targets aren't shown
+// 'testdata' is a directory on disk, but a link to a file here
+func TestLongFields_BackendLinks(t *testing.T) {
+	fsys := fstest.MapFS{
+		"notes.txt": {Data: []byte("notes"), Mode: 0o644},
+		"testdata":  {Data: []byte("notes.txt"), Mode: fs.ModeSymlink | 0o777},
+	}
+	now := time.Now()
+
+	opts := internal.Options{FS: linkFS{fsys}, NoColor: true}
+	link := retrieveEntries(t, ".", opts)[1]
+	if name := internal.LongFields(link, opts, now)[6]; name != "testdata -> notes.txt" {
+		t.Errorf("Expected the target's type from the backend, Got %q", name)
+	}
+
+	opts = internal.Options{FS: fsys, NoColor: true}
+	link = retrieveEntries(t, ".", opts)[1]
+	if name := internal.LongFields(link, opts, now)[6]; name != "testdata@" {
+		t.Errorf("Expected an unknown target to be left out, Got %q", name)
+	}
+}
+
+// Adds owners and inodes to an fstest.MapFS
+type ownedFS struct {
+	fstest.MapFS
+}
+
+func (o ownedFS) Lstat(name string) (ls.Entry, error) {
+	info, err := fs.Stat(o.MapFS, name)
+	if err != nil {
+		return ls.Entry{}, err
+	}
+	return ls.Entry{
+		Mode:    info.Mode(),
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+		Links:   1,
+		Owner:   "alice",
+		Group:   "staff",
+		Inode:   uint64(len(name)),
+	}, nil
+}
+
+// Test the public API lists any fs.FS, with full metadata from a MetaFS
+func TestList_FS(t *testing.T) {
+	entries, err := ls.List(context.Background(), []string{"bin"}, ls.Options{FS: makeMapFS(), Sort: ls.SortTime})
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Path != "bin/tool" || entries[0].Owner != "?" {
+		t.Errorf("Unexpected entries: %+v", entries)
+	}
+
+	entries, err = ls.List(context.Background(), []string{"docs"}, ls.Options{FS: ownedFS{makeMapFS()}, AlmostAll: true})
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := ls.Render(&buf, entries, ls.FormatLong); err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "alice staff") || entries[0].Inode != uint64(len("docs/.hidden")) {
+		t.Errorf("Expected owner and inode from Lstat, Got %q, %+v", buf.String(), entries)
+	}
+}
+
+// Test the public API groups directories first on any fs.FS
+func TestList_GroupDirectoriesFirst(t *testing.T) {
+	entries, err := ls.List(context.Background(), []string{"."}, ls.Options{FS: makeMapFS(), Reverse: true, GroupDirectoriesFirst: true})
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name)
+	}
+	if result := fmt.Sprint(names); result != "[docs bin README.md]" {
+		t.Errorf("Expected [docs bin README.md], Got %v", result)
+	}
+}
@@ -0,0 +1,190 @@
+package tests
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	internal "github.com/DavJesse/ls-clone/internal/ls"
+)
+
+// Writes a config file under a new XDG_CONFIG_HOME, returning that directory
+func writeConfig(t *testing.T, content string) string {
+	configHome := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(configHome, "my-ls"), 0o755); err != nil {
+		t.Fatalf("Failed to create config directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(configHome, "my-ls", "config"), []byte(content), 0o644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	return configHome
+}
+
+// Test config lines become long options, with comments, quotes and false values dropped
+func TestParseConfig(t *testing.T) {
+	config := "# team defaults\n\n" +
+		"almost-all\n" +
+		"sort = time   # newest first\n" +
+		"hide = \"*.o\"\n" +
+		"ignore='# notes'\n" +
+		"recursive = false\n" +
+		"reverse = true\n"
+
+	args, err := internal.ParseConfig(strings.NewReader(config), "config")
+	expect := "[--almost-all --sort=time --hide=*.o --ignore=# notes --reverse]"
+	if err != nil || fmt.Sprint(args) != expect {
+		t.Errorf("Expected %v, Got %v, %v", expect, args, err)
+	}
+}
+
+// Test config mistakes point at their line, and bad options stay inspectable
+func TestParseConfig_Errors(t *testing.T) {
+	_, err := internal.ParseConfig(strings.NewReader("all\ncolour = auto\n"), "config")
+	var optionErr *internal.OptionError
+	if !errors.As(err, &optionErr) || err.Error() != "config:2: unrecognized option '--colour=auto'" {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	_, err = internal.ParseConfig(strings.NewReader("all = maybe\n"), "config")
+	if err == nil || !strings.HasPrefix(err.Error(), "config:1: invalid value 'maybe' for 'all'") {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	_, err = internal.DefaultArgs(mapEnv(map[string]string{"MY_LS_OPTIONS": "-l notes.txt"}))
+	if err == nil || err.Error() != "MY_LS_OPTIONS: only options may be given, not 'notes.txt'" {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	// '.' would be listed ahead of the command line's paths, so it is refused like any other
+	_, err = internal.DefaultArgs(mapEnv(map[string]string{"MY_LS_OPTIONS": "."}))
+	if err == nil || err.Error() != "MY_LS_OPTIONS: only options may be given, not '.'" {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+// Test the config file is found under XDG_CONFIG_HOME, or under ~/.config without it
+func TestConfigPath(t *testing.T) {
+	testCases := []struct {
+		env    map[string]string
+		expect string
+	}{
+		{map[string]string{"XDG_CONFIG_HOME": "/etc/xdg", "HOME": "/home/alice"}, "/etc/xdg/my-ls/config"},
+		{map[string]string{"HOME": "/home/alice"}, "/home/alice/.config/my-ls/config"},
+		{map[string]string{"XDG_CONFIG_HOME": "relative", "HOME": "/home/alice"}, "/home/alice/.config/my-ls/config"},
+		{nil, ""},
+	}
+
+	for _, tc := range testCases {
+		if result := internal.ConfigPath(mapEnv(tc.env)); result != tc.expect {
+			t.Errorf("ConfigPath(%v) = %q; want %q", tc.env, result, tc.expect)
+		}
+	}
+}
+
+// Test the command line overrides MY_LS_OPTIONS, which overrides the config file,
+// and --no-config ignores both
+func TestRun_ConfigPrecedence(t *testing.T) {
+	tempDir := makeSortFixture(t)
+	configHome := writeConfig(t, "sort = size\n")
+
+	testCases := []struct {
+		name   string
+		env    map[string]string
+		args   []string
+		expect string
+	}{
+		{"config", map[string]string{"XDG_CONFIG_HOME": configHome}, nil, "[c.txt b.txt file10 file2 README a.go]"},
+		{"environment", map[string]string{"XDG_CONFIG_HOME": configHome, "MY_LS_OPTIONS": "--sort=time"}, nil, "[b.txt a.go c.txt README file10 file2]"},
+		{"command line", map[string]string{"XDG_CONFIG_HOME": configHome, "MY_LS_OPTIONS": "--sort=time"}, []string{"-X"}, "[file10 file2 README a.go b.txt c.txt]"},
+		{"combined", map[string]string{"XDG_CONFIG_HOME": configHome, "MY_LS_OPTIONS": "-r"}, nil, "[a.go README file2 file10 b.txt c.txt]"},
+		{"no config", map[string]string{"XDG_CONFIG_HOME": configHome, "MY_LS_OPTIONS": "--sort=time"}, []string{"--no-config"}, "[a.go b.txt c.txt file10 file2 README]"},
+	}
+
+	for _, tc := range testCases {
+		var stdout, stderr bytes.Buffer
+		status := internal.Run(append(tc.args, tempDir), mapEnv(tc.env), &stdout, &stderr)
+		result := fmt.Sprint(strings.Fields(stdout.String()))
+		if status != 0 || result != tc.expect {
+			t.Errorf("%v: Expected %v, Got %d, %v, %q", tc.name, tc.expect, status, result, stderr.String())
+		}
+	}
+}
+
+// Test -l on the command line overrides a default --format, as --format=long would
+func TestRun_ConfigFormatPrecedence(t *testing.T) {
+	tempDir := makeSortFixture(t)
+	env := mapEnv(map[string]string{"MY_LS_OPTIONS": "--format=json"})
+
+	var stdout, stderr bytes.Buffer
+	if status := internal.Run([]string{"-l", tempDir}, env, &stdout, &stderr); status != 0 {
+		t.Fatalf("Expected status 0, Got %d, %q", status, stderr.String())
+	}
+	if !strings.HasPrefix(stdout.String(), "total ") || strings.Contains(stdout.String(), "{") {
+		t.Errorf("Expected a long listing, Got %q", stdout.String())
+	}
+
+	stdout.Reset()
+	if status := internal.Run([]string{"--format=long", tempDir}, env, &stdout, &stderr); status != 0 || !strings.HasPrefix(stdout.String(), "total ") {
+		t.Errorf("Expected --format=long to do the same, Got %d, %q", status, stdout.String())
+	}
+}
+
+// Test the defaults a team would keep, '--group-directories-first -h --color=auto',
+// from either source: piped output is left uncolored unless the command line asks for color
+func TestRun_ColorAutoDefaults(t *testing.T) {
+	tempDir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(tempDir, "z-dir"), 0o755); err != nil {
+		t.Fatalf("Failed to create test directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, "a.bin"), make([]byte, 2048), 0o644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	configHome := writeConfig(t, "group-directories-first\nhuman-readable\ncolor = auto\n")
+
+	envs := []map[string]string{
+		{"MY_LS_OPTIONS": "--group-directories-first -h --color=auto"},
+		{"XDG_CONFIG_HOME": configHome},
+	}
+	for _, env := range envs {
+		var stdout, stderr bytes.Buffer
+		if status := internal.Run([]string{"-l", tempDir}, mapEnv(env), &stdout, &stderr); status != 0 {
+			t.Fatalf("%v: Expected status 0, Got %d, %q", env, status, stderr.String())
+		}
+		lines := strings.Split(stdout.String(), "\n")
+		if len(lines) < 3 || !strings.HasSuffix(lines[1], " z-dir/") || !strings.Contains(lines[2], " 2.0K ") {
+			t.Errorf("%v: Expected z-dir first and a human-readable size, Got %q", env, stdout.String())
+		}
+		if strings.Contains(stdout.String(), "\x1b[") {
+			t.Errorf("%v: Expected no colors when piped, Got %q", env, stdout.String())
+		}
+
+		stdout.Reset()
+		internal.Run([]string{"--color", tempDir}, mapEnv(env), &stdout, &stderr)
+		if !strings.Contains(stdout.String(), "\x1b[01;34mz-dir") {
+			t.Errorf("%v: Expected --color to override the default, Got %q", env, stdout.String())
+		}
+	}
+}
+
+// Test a bad default fails the run, unless --no-config is given
+func TestRun_ConfigError(t *testing.T) {
+	tempDir := makeSortFixture(t)
+	configHome := writeConfig(t, "colour = auto\n")
+	env := mapEnv(map[string]string{"XDG_CONFIG_HOME": configHome})
+
+	var stdout, stderr bytes.Buffer
+	status := internal.Run([]string{tempDir}, env, &stdout, &stderr)
+	expect := "my-ls: " + filepath.Join(configHome, "my-ls", "config") + ":1: unrecognized option '--colour=auto'\nTry 'my-ls --help' for more information.\n"
+	if status != 2 || stderr.String() != expect {
+		t.Errorf("Expected status 2 and %q, Got %d, %q", expect, status, stderr.String())
+	}
+
+	stderr.Reset()
+	if status := internal.Run([]string{"--no-config", tempDir}, env, &stdout, &stderr); status != 0 {
+		t.Errorf("Expected --no-config to skip the config, Got %d, %q", status, stderr.String())
+	}
+}
@@ -0,0 +1,113 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	internal "github.com/DavJesse/ls-clone/internal/ls"
+)
+
+// Test CSV output quotes names holding commas, quotes and newlines
+func TestRenderCSV_Quoting(t *testing.T) {
+	tempDir := t.TempDir()
+	names := []string{"a,b.txt", "line\nbreak.txt", "say \"hi\".txt"}
+	for _, name := range names {
+		if err := os.WriteFile(filepath.Join(tempDir, name), []byte("12345"), 0o644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+
+	var buf bytes.Buffer
+	opts := internal.Options{Fields: []string{"name", "size", "mode"}}
+	if err := internal.RenderCSV(&buf, retrieveEntries(t, tempDir, internal.Options{Recursive: true}), opts); err != nil {
+		t.Fatalf("RenderCSV failed: %v", err)
+	}
+
+	if !strings.Contains(buf.String(), "\"a,b.txt\",5,-rw-r--r--\r\n") {
+		t.Errorf("Expected quoted comma in output, Got %q", buf.String())
+	}
+	if !strings.Contains(buf.String(), "\"say \"\"hi\"\".txt\"") {
+		t.Errorf("Expected doubled quotes in output, Got %q", buf.String())
+	}
+
+	// Reading the output back must give the original names
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("Output is not valid CSV: %v", err)
+	}
+	if len(records) != 4 || strings.Join(records[0], ",") != "name,size,mode" {
+		t.Fatalf("Unexpected records: %q", records)
+	}
+	for i, name := range names {
+		if strings.ReplaceAll(records[i+1][0], "\r\n", "\n") != name {
+			t.Errorf("Expected %q, Got %q", name, records[i+1][0])
+		}
+	}
+}
+
+// Test TSV output separates fields with tabs and uses default fields
+func TestRenderTSV_DefaultFields(t *testing.T) {
+	tempDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tempDir, "file.txt"), nil, 0o600); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := internal.RenderTSV(&buf, retrieveEntries(t, tempDir, internal.Options{Recursive: true}), internal.Options{}); err != nil {
+		t.Fatalf("RenderTSV failed: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if lines[0] != "name\tsize\tmtime\towner\tmode" {
+		t.Errorf("Unexpected header: %q", lines[0])
+	}
+	if !strings.HasPrefix(lines[1], "file.txt\t0\t") || !strings.HasSuffix(lines[1], "\t-rw-------") {
+		t.Errorf("Unexpected record: %q", lines[1])
+	}
+}
+
+// Test -i puts the inode ahead of the default fields, but leaves --fields as given
+func TestRenderCSV_Inode(t *testing.T) {
+	tempDir := t.TempDir()
+	file := filepath.Join(tempDir, "file.txt")
+	if err := os.WriteFile(file, nil, 0o600); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	files := retrieveEntries(t, tempDir, internal.Options{})
+	inode := strconv.FormatUint(files[0].Meta.Inode, 10)
+
+	var buf bytes.Buffer
+	if err := internal.RenderCSV(&buf, files, internal.ParseFlag("-i")); err != nil {
+		t.Fatalf("RenderCSV failed: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\r\n")
+	if lines[0] != "inode,name,size,mtime,owner,mode" || !strings.HasPrefix(lines[1], inode+",file.txt,0,") {
+		t.Errorf("Expected the inode first, Got %q", lines)
+	}
+
+	buf.Reset()
+	if err := internal.RenderCSV(&buf, files, internal.Options{Inode: true, Fields: []string{"name"}}); err != nil {
+		t.Fatalf("RenderCSV failed: %v", err)
+	}
+	if buf.String() != "name\r\nfile.txt\r\n" {
+		t.Errorf("Expected --fields to be kept, Got %q", buf.String())
+	}
+}
+
+// Test --fields rejects unknown columns
+func TestParseArgs_Fields(t *testing.T) {
+	opts, _, err := internal.ParseArgs([]string{"--format=csv", "--fields=name,inode"})
+	if err != nil || opts.Format != "csv" || strings.Join(opts.Fields, ",") != "name,inode" {
+		t.Errorf("Unexpected result: %+v, %v", opts, err)
+	}
+
+	_, _, err = internal.ParseArgs([]string{"--fields=name,colour"})
+	if err == nil {
+		t.Errorf("Expected error for unknown field, Got nil")
+	}
+}
@@ -0,0 +1,211 @@
+package tests
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	internal "github.com/DavJesse/ls-clone/internal/ls"
+)
+
+// Test file operands are split from directories and keep the path as given
+func TestSplitOperands(t *testing.T) {
+	tempDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(tempDir, "a", "sub"), 0o755); err != nil {
+		t.Fatalf("Failed to create test directories: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, "a", "b.txt"), []byte("hello"), 0o644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	if err := os.Symlink("a", filepath.Join(tempDir, "link")); err != nil {
+		t.Fatalf("Failed to create symbolic link: %v", err)
+	}
+	a, file, link := tempDir+"/a", tempDir+"/./a/b.txt", tempDir+"/link"
+
+	files, dirs, err := internal.SplitOperands([]string{link, file, a}, internal.Options{})
+	if err != nil {
+		t.Fatalf("SplitOperands failed: %v", err)
+	}
+	if len(files) != 1 || files[0].Name != file || fmt.Sprint(dirs) != fmt.Sprint([]string{a, link}) {
+		t.Errorf("Unexpected split: %v, %v", entryNames(files), dirs)
+	}
+
+	// With -l, links to directories are shown as themselves
+	files, dirs, _ = internal.SplitOperands([]string{link}, internal.ParseFlag("-l"))
+	if len(files) != 1 || len(dirs) != 0 {
+		t.Errorf("Expected link as a file with -l, Got %v, %v", entryNames(files), dirs)
+	}
+
+	if _, _, err := internal.SplitOperands([]string{tempDir + "/missing"}, internal.Options{}); err == nil {
+		t.Errorf("Expected error for missing operand, Got nil")
+	}
+}
+
+// Test -l rows of a file operand and a symbolic link
+func TestRenderLong(t *testing.T) {
+	tempDir := t.TempDir()
+	filePath := filepath.Join(tempDir, "b.txt")
+	if err := os.WriteFile(filePath, []byte("hello"), 0o640); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	if err := os.Symlink("b.txt", filepath.Join(tempDir, "link")); err != nil {
+		t.Fatalf("Failed to create symbolic link: %v", err)
+	}
+	mtime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.Local)
+	if err := os.Chtimes(filePath, mtime, mtime); err != nil {
+		t.Fatalf("Failed to set times: %v", err)
+	}
+
+	file, err := internal.RetrieveOperand(filePath)
+	if err != nil {
+		t.Fatalf("RetrieveOperand failed: %v", err)
+	}
+	link, _ := internal.RetrieveOperand(filepath.Join(tempDir, "link"))
+
+	var buf bytes.Buffer
+	if err := internal.RenderEntries(&buf, []internal.FileInfo{file, link}, internal.ParseFlag("-l")); err != nil {
+		t.Fatalf("RenderEntries failed: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	owner := file.Meta.UserID + " " + file.Meta.GroupID
+	expect := "-rw-r----- 1 " + owner + " 5 Jan  2  2020 " + filePath
+	if lines[0] != expect {
+		t.Errorf("Expected: %q\nGot: %q", expect, lines[0])
+	}
+	if !strings.HasPrefix(lines[1], "lrwxrwxrwx 1 "+owner+" 5 ") || !strings.HasSuffix(lines[1], "link\033[0m -> b.txt") {
+		t.Errorf("Unexpected link row: %q", lines[1])
+	}
+}
+
+// Test -R sections are headed by their path, and -l adds totals
+func TestRenderDirectory_Recursive(t *testing.T) {
+	tempDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(tempDir, "sub"), 0o755); err != nil {
+		t.Fatalf("Failed to create test directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, "sub", "file.txt"), nil, 0o644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	var buf bytes.Buffer
+	opts := internal.ParseFlag("-R")
+	if err := internal.RenderDirectory(&buf, tempDir, retrieveEntries(t, tempDir, opts), opts, true); err != nil {
+		t.Fatalf("RenderDirectory failed: %v", err)
+	}
+
+	expect := tempDir + ":\n\033[01;34msub\033[0m/\n\n" + tempDir + "/sub:\nfile.txt\n"
+	if buf.String() != expect {
+		t.Errorf("Expected: %q\nGot: %q", expect, buf.String())
+	}
+}
+
+// Test recent times show the time of day, others the year
+func TestFormatTime(t *testing.T) {
+	now := time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		t      time.Time
+		expect string
+	}{
+		{time.Date(2024, 6, 1, 9, 5, 0, 0, time.UTC), "Jun  1 09:05"},
+		{time.Date(2023, 11, 20, 9, 5, 0, 0, time.UTC), "Nov 20  2023"},
+		{time.Date(2024, 7, 1, 9, 5, 0, 0, time.UTC), "Jul  1  2024"},
+	}
+
+	for _, tc := range testCases {
+		if result := internal.FormatTime(tc.t, now); result != tc.expect {
+			t.Errorf("FormatTime(%v) = %q; want %q", tc.t, result, tc.expect)
+		}
+	}
+}
+
+// Test -h sizes round up, with one decimal below 10, as ls -h shows them
+func TestHumanSize(t *testing.T) {
+	testCases := []struct {
+		size   int64
+		expect string
+	}{
+		{0, "0"},
+		{1023, "1023"},
+		{1024, "1.0K"},
+		{1025, "1.1K"},
+		{10239, "10K"},
+		{30000, "30K"},
+		{1048575, "1.0M"},
+		{5<<20 + 1, "5.1M"},
+		{1 << 30, "1.0G"},
+	}
+
+	for _, tc := range testCases {
+		if result := internal.HumanSize(tc.size); result != tc.expect {
+			t.Errorf("HumanSize(%d) = %q; want %q", tc.size, result, tc.expect)
+		}
+	}
+}
+
+// Records each write made to it, to see when buffered output is flushed
+type writeRecorder struct {
+	writes []string
+}
+
+func (r *writeRecorder) Write(p []byte) (int, error) {
+	r.writes = append(r.writes, string(p))
+	return len(p), nil
+}
+
+// Test buffered output reaches the writer once per directory section
+func TestRenderDirectory_FlushPerSection(t *testing.T) {
+	tempDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(tempDir, "sub"), 0o755); err != nil {
+		t.Fatalf("Failed to create test directory: %v", err)
+	}
+	for _, name := range []string{"a.txt", "b.txt", filepath.Join("sub", "c.txt")} {
+		if err := os.WriteFile(filepath.Join(tempDir, name), nil, 0o644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+
+	recorder := &writeRecorder{}
+	out := bufio.NewWriter(recorder)
+	opts := internal.Options{Recursive: true, NoColor: true}
+	if err := internal.RenderDirectory(out, tempDir, retrieveEntries(t, tempDir, opts), opts, true); err != nil {
+		t.Fatalf("RenderDirectory failed: %v", err)
+	}
+
+	expect := []string{tempDir + ":\na.txt\nb.txt\nsub/\n", "\n" + tempDir + "/sub:\nc.txt\n"}
+	if fmt.Sprintf("%q", recorder.writes) != fmt.Sprintf("%q", expect) {
+		t.Errorf("Expected writes %q, Got %q", expect, recorder.writes)
+	}
+}
+
+// Test the plain and hard link listings write to the given writer
+func TestUnravelFiles_Writer(t *testing.T) {
+	file := internal.FileInfo{Name: "a.txt", Path: "dir/a.txt"}
+	file.Meta.Inode = 7
+	link := internal.FileInfo{Name: "b.txt", Path: "dir/sub/b.txt"}
+	link.Meta.Inode = 7
+	dir := internal.FileInfo{Name: "sub", RecursiveList: []internal.FileInfo{link}}
+	dir.Meta.Mode = os.ModeDir | 0o755
+
+	var buf bytes.Buffer
+	if err := internal.UnravelFiles(&buf, []internal.FileInfo{file, dir}, internal.Options{NoColor: true}); err != nil {
+		t.Fatalf("UnravelFiles failed: %v", err)
+	}
+	if buf.String() != "a.txt\nsub/\nb.txt\n" {
+		t.Errorf("Expected %q, Got %q", "a.txt\nsub/\nb.txt\n", buf.String())
+	}
+
+	buf.Reset()
+	if err := internal.PrintHardLinks(&buf, [][]internal.FileInfo{{file, link}}); err != nil {
+		t.Fatalf("PrintHardLinks failed: %v", err)
+	}
+	if buf.String() != "inode 7: dir/a.txt dir/sub/b.txt\n" {
+		t.Errorf("Expected %q, Got %q", "inode 7: dir/a.txt dir/sub/b.txt\n", buf.String())
+	}
+}
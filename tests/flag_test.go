@@ -1,7 +1,7 @@
 package tests
 
 import (
-	internal "my-ls/internal/ls"
+	internal "github.com/DavJesse/ls-clone/internal/ls"
 	"testing"
 )
 
@@ -65,9 +65,20 @@ func TestIsValid_ValidFlagWithSpaces(t *testing.T) {
 }
 
 // Test multiple-hyphens input
+// Two hyphens start a long option, so only names of real options are valid
 func TestIsValid_MultipleHyphens(t *testing.T) {
-	result, _ := internal.IsValidFlag("--all")
-	if result {
+	if result, err := internal.IsValidFlag("--all"); !result {
+		t.Errorf("Expected true; Got %v (%v)", result, err)
+	}
+	if result, _ := internal.IsValidFlag("---all"); result {
 		t.Errorf("Expected false; Got %v", result)
 	}
 }
+
+// Test the error names the first character that isn't a flag
+func TestIsValidFlag_NamesInvalidCharacter(t *testing.T) {
+	_, err := internal.IsValidFlag("-lamz")
+	if err == nil || err.Error() != "invalid option -- 'm'" {
+		t.Errorf("Expected \"invalid option -- 'm'\"; Got %v", err)
+	}
+}
@@ -0,0 +1,131 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	internal "github.com/DavJesse/ls-clone/internal/ls"
+)
+
+// Builds a fixture repository from a map of relative paths to contents
+// Paths ending in '/' become directories
+func makeRepoFixture(t *testing.T, files map[string]string) string {
+	tempDir := t.TempDir()
+	for name, content := range files {
+		full := filepath.Join(tempDir, name)
+		if strings.HasSuffix(name, "/") {
+			if err := os.MkdirAll(full, 0o755); err != nil {
+				t.Fatalf("Failed to create test directory: %v", err)
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+			t.Fatalf("Failed to create test directory: %v", err)
+		}
+		if err := os.WriteFile(full, []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+	return tempDir
+}
+
+var repoFixture = map[string]string{
+	".git/info/exclude":    "secret.txt\n",
+	".gitignore":           "# build output\n*.log\n!keep.log\nbuild/\n/root-only.txt\n**/gen/**\ndocs/**/*.tmp\n\\#hash\n",
+	"app.go":               "",
+	"app.log":              "",
+	"keep.log":             "",
+	"secret.txt":           "",
+	"root-only.txt":        "",
+	"#hash":                "",
+	"build/out.bin":        "",
+	"notbuild":             "",
+	"docs/a/b/draft.tmp":   "",
+	"docs/draft.tmp":       "",
+	"docs/readme.txt":      "",
+	"sub/root-only.txt":    "",
+	"sub/.gitignore":       "local.txt\n!debug.log\nbuild\n",
+	"sub/local.txt":        "",
+	"sub/debug.log":        "",
+	"sub/other.log":        "",
+	"sub/gen/code.go":      "",
+	"sub/build":            "",
+	"sub/nested/local.txt": "",
+}
+
+// Test the matcher against negation, anchoring, directory-only and '**' rules
+func TestGitIgnoreMatcher_Ignored(t *testing.T) {
+	root := makeRepoFixture(t, repoFixture)
+	matcher := internal.NewGitIgnoreMatcher()
+
+	testCases := []struct {
+		path   string
+		isDir  bool
+		expect bool
+	}{
+		{"app.go", false, false},
+		{"app.log", false, true},
+		{"keep.log", false, false},
+		{"secret.txt", false, true},
+		{"root-only.txt", false, true},
+		{"sub/root-only.txt", false, false},
+		{"#hash", false, true},
+		{"build", true, true},
+		{"build/out.bin", false, true},
+		{"notbuild", false, false},
+		{"docs/draft.tmp", false, true},
+		{"docs/a/b/draft.tmp", false, true},
+		{"docs/readme.txt", false, false},
+		{"sub/local.txt", false, true},
+		{"sub/nested/local.txt", false, true},
+		{"sub/debug.log", false, false},
+		{"sub/other.log", false, true},
+		{"sub/gen", true, false},
+		{"sub/gen/code.go", false, true},
+		{"sub/build", false, true},
+	}
+
+	for _, tc := range testCases {
+		dir, name := filepath.Split(filepath.Join(root, tc.path))
+		if result := matcher.Ignored(dir, name, tc.isDir); result != tc.expect {
+			t.Errorf("Ignored(%v) = %v; want %v", tc.path, result, tc.expect)
+		}
+	}
+}
+
+// Test --gitignore hides ignored entries and skips ignored directories during -R
+func TestRetrieveEntries_GitIgnore(t *testing.T) {
+	root := makeRepoFixture(t, repoFixture)
+
+	opts, _, err := internal.ParseArgs([]string{"-R", "--gitignore"})
+	if err != nil {
+		t.Fatalf("ParseArgs failed: %v", err)
+	}
+
+	var names []string
+	var walk func(files []internal.FileInfo)
+	walk = func(files []internal.FileInfo) {
+		for i := range files {
+			rel, _ := filepath.Rel(root, files[i].Path)
+			names = append(names, rel)
+			walk(files[i].RecursiveList)
+		}
+	}
+	walk(retrieveEntries(t, root, opts))
+
+	expect := "app.go docs docs/a docs/a/b docs/readme.txt keep.log notbuild sub sub/debug.log sub/gen sub/nested sub/root-only.txt"
+	if result := strings.Join(names, " "); result != expect {
+		t.Errorf("Expected: %v\nGot: %v", expect, result)
+	}
+}
+
+// Test entries outside a repository are never ignored
+func TestGitIgnoreMatcher_OutsideRepository(t *testing.T) {
+	tempDir := makeRepoFixture(t, map[string]string{".gitignore": "*\n", "file.txt": ""})
+
+	if internal.NewGitIgnoreMatcher().Ignored(tempDir, "file.txt", false) {
+		t.Errorf("Expected false outside a repository, Got true")
+	}
+}
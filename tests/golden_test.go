@@ -0,0 +1,265 @@
+//go:build linux
+
+// The goldens come from GNU coreutils, and the fixture's link times are set with utimensat,
+// so this comparison runs on Linux only
+
+package tests
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+	"unsafe"
+
+	internal "github.com/DavJesse/ls-clone/internal/ls"
+)
+
+// Rewrites the golden files from the system ls instead of comparing against them:
+// go test ./tests -run TestGolden -update
+var update = flag.Bool("update", false, "regenerate golden files from the system ls")
+
+// Entries of the golden fixture, parents before their children
+// Every directory holding subdirectories also holds a five-digit file, so the size
+// column keeps its width whatever size the filesystem gives directories
+var goldenFixture = []struct {
+	name   string
+	mode   fs.FileMode
+	size   int
+	target string
+	mtime  string
+}{
+	{".hidden", 0o644, 0, "", "2023-01-05 08:00"},
+	{"alpha.txt", 0o600, 6, "", "2023-02-11 09:30"},
+	{"big.bin", 0o644, 30000, "", "2023-03-02 10:15"},
+	{"café", 0o644, 12, "", "2023-04-09 11:00"},
+	{"with space", 0o644, 3, "", "2023-05-20 12:45"},
+	{"日本語.txt", 0o644, 9, "", "2023-06-14 13:20"},
+	{"run.sh", 0o755, 10, "", "2023-07-01 14:00"},
+	{"notes.md", 0o644, 42, "", "2023-07-19 06:10"},
+	{"v10.txt", 0o644, 1, "", "2023-08-02 15:05"},
+	{"v9.txt", 0o644, 2, "", "2023-08-03 15:05"},
+	{"pipe", fs.ModeNamedPipe | 0o644, 0, "", "2023-09-12 16:40"},
+	{"link", fs.ModeSymlink | 0o777, 0, "alpha.txt", "2022-01-01 00:00"},
+	{"broken", fs.ModeSymlink | 0o777, 0, "missing", "2022-02-01 00:00"},
+	{"dirlink", fs.ModeSymlink | 0o777, 0, "sub", "2022-03-01 00:00"},
+	{"sub.md", 0o644, 7, "", "2023-10-05 07:00"},
+	{"sub", fs.ModeDir | 0o755, 0, "", "2023-10-04 17:25"},
+	{"sub/inner.go", 0o644, 12000, "", "2023-10-01 18:00"},
+	{"sub/deep", fs.ModeDir | 0o700, 0, "", "2023-10-02 19:00"},
+	{"sub/deep/leaf", 0o444, 0, "", "2023-10-03 20:00"},
+	{"sub/empty", fs.ModeDir | 0o755, 0, "", "2023-10-03 21:00"},
+	{"quoting", fs.ModeDir | 0o755, 0, "", "2023-11-21 21:30"},
+	{"quoting/tab\there", 0o644, 0, "", "2023-11-20 22:00"},
+	{"quoting/quo\"te", 0o644, 0, "", "2023-11-20 22:00"},
+	{"quoting/back\\slash", 0o644, 0, "", "2023-11-20 22:00"},
+}
+
+// Flag combinations compared against the goldens, named after their golden file
+// Non-ASCII names are escaped byte by byte by ls in the C locale, so -b and -Q list ASCII names only
+var goldenCases = []struct {
+	name string
+	args []string
+}{
+	{"default", nil},
+	{"almost_all", []string{"-A"}},
+	{"all", []string{"-a"}},
+	{"reverse", []string{"-r"}},
+	{"time", []string{"-t"}},
+	{"time_reverse", []string{"-tr"}},
+	{"extension", []string{"-X"}},
+	{"version", []string{"-v"}},
+	{"long", []string{"-l"}},
+	{"long_reverse", []string{"-lr"}},
+	{"long_time", []string{"-lt"}},
+	{"long_size_operands", []string{"-lS", "alpha.txt", "big.bin", "run.sh", "café"}},
+	{"long_human_operands", []string{"-lh", "alpha.txt", "big.bin", "sub/inner.go", "notes.md"}},
+	{"recursive", []string{"-R"}},
+	{"long_recursive", []string{"-lR"}},
+	{"directory", []string{"-d"}},
+	{"long_directory", []string{"-ld", ".", "sub", "dirlink"}},
+	{"operands", []string{"sub", "alpha.txt", "link", "quoting"}},
+	{"long_operands", []string{"-l", "alpha.txt", "link", "sub/inner.go"}},
+	{"escape", []string{"-b", "quoting"}},
+	{"quote", []string{"-Q", "quoting"}},
+	{"missing", []string{"nope", "alpha.txt"}},
+	{"missing_directory", []string{"nope", "sub"}},
+	{"long_empty_directory", []string{"-l", "sub/empty"}},
+	{"long_empty_operands", []string{"-l", "sub/empty", "sub/deep"}},
+	{"recursive_empty_directory", []string{"-R", "sub/empty"}},
+	{"group_directories_first", []string{"--group-directories-first"}},
+	{"group_time_reverse", []string{"-tr", "--group-directories-first"}},
+	{"group_extension", []string{"-X", "--group-directories-first"}},
+	{"group_long", []string{"-l", "--group-directories-first"}},
+}
+
+// Sets the times of 'path' itself, not of the file a symbolic link points to
+func lutimes(path string, mtime time.Time) error {
+	name, err := syscall.BytePtrFromString(path)
+	if err != nil {
+		return err
+	}
+	times := [2]syscall.Timespec{syscall.NsecToTimespec(mtime.UnixNano()), syscall.NsecToTimespec(mtime.UnixNano())}
+	dirfd := -0x64 // AT_FDCWD: relative paths name files in the working directory
+	_, _, errno := syscall.Syscall6(syscall.SYS_UTIMENSAT, uintptr(dirfd), uintptr(unsafe.Pointer(name)), uintptr(unsafe.Pointer(&times)), 0x100 /* AT_SYMLINK_NOFOLLOW */, 0, 0)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// Builds the golden fixture, with every mode and time fixed
+func makeGoldenFixture(t *testing.T) string {
+	root := t.TempDir()
+
+	for _, entry := range goldenFixture {
+		path := filepath.Join(root, entry.name)
+		var err error
+		switch {
+		case entry.mode.IsDir():
+			err = os.Mkdir(path, 0o755)
+		case entry.mode&fs.ModeSymlink != 0:
+			err = os.Symlink(entry.target, path)
+		case entry.mode&fs.ModeNamedPipe != 0:
+			err = syscall.Mkfifo(path, 0o644)
+		default:
+			err = os.WriteFile(path, make([]byte, entry.size), 0o644)
+		}
+		if err != nil {
+			t.Fatalf("Failed to create %q: %v", entry.name, err)
+		}
+	}
+
+	// Children first, as creating an entry updates its directory's time
+	for i := len(goldenFixture) - 1; i >= 0; i-- {
+		entry := goldenFixture[i]
+		path := filepath.Join(root, entry.name)
+		mtime, err := time.Parse("2006-01-02 15:04", entry.mtime)
+		if err != nil {
+			t.Fatalf("Bad fixture time %q: %v", entry.mtime, err)
+		}
+		if entry.mode&fs.ModeSymlink == 0 {
+			if err := os.Chmod(path, entry.mode.Perm()); err != nil {
+				t.Fatalf("Failed to set mode of %q: %v", entry.name, err)
+			}
+		}
+		if err := lutimes(path, mtime); err != nil {
+			t.Fatalf("Failed to set time of %q: %v", entry.name, err)
+		}
+	}
+	if err := lutimes(root, time.Date(2023, 12, 24, 23, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("Failed to set time of fixture: %v", err)
+	}
+	return root
+}
+
+var (
+	ansiCodes  = regexp.MustCompile("\x1b\\[[0-9;]*m")
+	ownerGroup = regexp.MustCompile(`(?m)^([-dlpcbs][-rwxsStT]{9}\S*\s+\d+) \S+ \S+ `)
+	dirSize    = regexp.MustCompile(`(?m)^(d\S* +\d+ owner group )( *\d+)`)
+	blockTotal = regexp.MustCompile(`(?m)^total \d+$`)
+)
+
+// Removes what differs between machines rather than between implementations:
+// colors, owner names, directory sizes and block counts
+func normalizeListing(output string) string {
+	output = ansiCodes.ReplaceAllString(output, "")
+	output = ownerGroup.ReplaceAllString(output, "$1 owner group ")
+	output = dirSize.ReplaceAllStringFunc(output, func(line string) string {
+		match := dirSize.FindStringSubmatch(line)
+		return match[1] + fmt.Sprintf("%*s", len(match[2]), "-")
+	})
+	return blockTotal.ReplaceAllString(output, "total -")
+}
+
+// Lays out a run as stored in a golden file: stdout, then stderr and the exit status when there are any
+func formatGolden(stdout, stderr string, status int) string {
+	golden := normalizeListing(stdout)
+	if stderr != "" {
+		golden += "--- stderr\n" + stderr
+	}
+	if status != 0 {
+		golden += fmt.Sprintf("--- exit %d\n", status)
+	}
+	return golden
+}
+
+// Runs the system ls the way my-ls lists by default: with indicators, without colors
+func runSystemLs(t *testing.T, root string, args []string) string {
+	cmd := exec.Command("ls", append([]string{"-F", "--color=never"}, args...)...)
+	cmd.Dir = root
+	cmd.Env = append(os.Environ(), "LC_ALL=C", "TZ=UTC")
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	status := 0
+	if err := cmd.Run(); err != nil {
+		exitErr, ok := err.(*exec.ExitError)
+		if !ok {
+			t.Fatalf("Failed to run ls: %v", err)
+		}
+		status = exitErr.ExitCode()
+	}
+
+	// Errors are reported under my-ls's own name
+	errors := regexp.MustCompile(`(?m)^ls: `).ReplaceAllString(stderr.String(), "my-ls: ")
+	return formatGolden(stdout.String(), errors, status)
+}
+
+// Runs my-ls in-process from inside 'root'
+func runMyLs(t *testing.T, root string, args []string) string {
+	workDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	if err := os.Chdir(root); err != nil {
+		t.Fatalf("Failed to enter fixture: %v", err)
+	}
+	defer os.Chdir(workDir)
+
+	var stdout, stderr bytes.Buffer
+	status := internal.Run(args, mapEnv(map[string]string{"LC_ALL": "C", "TZ": "UTC"}), &stdout, &stderr)
+	return formatGolden(stdout.String(), stderr.String(), status)
+}
+
+// Test real listings of a fixture tree match what coreutils ls prints for it
+func TestGolden(t *testing.T) {
+	root := makeGoldenFixture(t)
+
+	// Times are shown in UTC, as the goldens were recorded with TZ=UTC
+	local := time.Local
+	time.Local = time.UTC
+	defer func() { time.Local = local }()
+
+	for _, tc := range goldenCases {
+		t.Run(tc.name, func(t *testing.T) {
+			goldenPath := filepath.Join("testdata", "golden", tc.name+".golden")
+
+			if *update {
+				if err := os.MkdirAll(filepath.Dir(goldenPath), 0o755); err != nil {
+					t.Fatalf("Failed to create golden directory: %v", err)
+				}
+				if err := os.WriteFile(goldenPath, []byte(runSystemLs(t, root, tc.args)), 0o644); err != nil {
+					t.Fatalf("Failed to write golden file: %v", err)
+				}
+			}
+
+			expect, err := os.ReadFile(goldenPath)
+			if err != nil {
+				t.Fatalf("Failed to read golden file (regenerate with -update): %v", err)
+			}
+			result := runMyLs(t, root, tc.args)
+			if result != string(expect) {
+				t.Errorf("my-ls %v differs from %v\nExpected:\n%s\nGot:\n%s", strings.Join(tc.args, " "), goldenPath, expect, result)
+			}
+		})
+	}
+}
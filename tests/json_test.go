@@ -0,0 +1,158 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	internal "github.com/DavJesse/ls-clone/internal/ls"
+)
+
+// Test symbolic and octal modes, including special bits
+func TestSymbolicMode(t *testing.T) {
+	testCases := []struct {
+		mode     os.FileMode
+		symbolic string
+		octal    string
+	}{
+		{0o644, "-rw-r--r--", "0644"},
+		{os.ModeDir | 0o755, "drwxr-xr-x", "0755"},
+		{os.ModeSymlink | 0o777, "lrwxrwxrwx", "0777"},
+		{os.ModeSetuid | 0o755, "-rwsr-xr-x", "4755"},
+		{os.ModeSetgid | 0o644, "-rw-r-Sr--", "2644"},
+		{os.ModeDir | os.ModeSticky | 0o777, "drwxrwxrwt", "1777"},
+	}
+
+	for _, tc := range testCases {
+		if result := internal.SymbolicMode(tc.mode); result != tc.symbolic {
+			t.Errorf("SymbolicMode(%v) = %v; want %v", tc.mode, result, tc.symbolic)
+		}
+		if result := internal.OctalMode(tc.mode); result != tc.octal {
+			t.Errorf("OctalMode(%v) = %v; want %v", tc.mode, result, tc.octal)
+		}
+	}
+}
+
+// Test JSON output holds one object per entry with its metadata
+func TestRenderJSON(t *testing.T) {
+	tempDir := t.TempDir()
+	filePath := filepath.Join(tempDir, "file.txt")
+	if err := os.WriteFile(filePath, []byte("content"), 0o640); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	if err := os.Symlink("file.txt", filepath.Join(tempDir, "link")); err != nil {
+		t.Fatalf("Failed to create symbolic link: %v", err)
+	}
+	mtime := time.Date(2020, 1, 2, 3, 4, 5, 6, time.UTC)
+	if err := os.Chtimes(filePath, mtime, mtime); err != nil {
+		t.Fatalf("Failed to set times: %v", err)
+	}
+
+	var buf bytes.Buffer
+	files := retrieveEntries(t, tempDir, internal.Options{Recursive: true})
+	if err := internal.RenderJSON(&buf, files, internal.Options{}); err != nil {
+		t.Fatalf("RenderJSON failed: %v", err)
+	}
+
+	var entries []internal.JSONEntry
+	if err := json.Unmarshal(buf.Bytes(), &entries); err != nil {
+		t.Fatalf("Output is not valid JSON: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, Got %d", len(entries))
+	}
+
+	file := entries[0]
+	if file.Name != "file.txt" || file.Type != "file" || file.Mode != "0640" || file.Size != 7 || file.Dir != tempDir {
+		t.Errorf("Unexpected file entry: %+v", file)
+	}
+	if parsed, _ := time.Parse(time.RFC3339Nano, file.ModTime); !parsed.Equal(mtime) {
+		t.Errorf("Expected mtime %v, Got %v", mtime, file.ModTime)
+	}
+
+	link := entries[1]
+	if link.Type != "symlink" || link.LinkTarget != "file.txt" {
+		t.Errorf("Unexpected link entry: %+v", link)
+	}
+}
+
+// Test NDJSON output holds one line per entry, descending with -R
+func TestRenderNDJSON_Recursive(t *testing.T) {
+	tempDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(tempDir, "sub"), 0o755); err != nil {
+		t.Fatalf("Failed to create test directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, "sub", "file.txt"), nil, 0o644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	var buf bytes.Buffer
+	files := retrieveEntries(t, tempDir, internal.Options{Recursive: true})
+	if err := internal.RenderNDJSON(&buf, files, internal.ParseFlag("-R")); err != nil {
+		t.Fatalf("RenderNDJSON failed: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 lines, Got %d", len(lines))
+	}
+
+	var entry internal.JSONEntry
+	if err := json.Unmarshal([]byte(lines[1]), &entry); err != nil {
+		t.Fatalf("Line is not valid JSON: %v", err)
+	}
+	if entry.Name != "file.txt" || entry.Dir != filepath.Join(tempDir, "sub") {
+		t.Errorf("Unexpected nested entry: %+v", entry)
+	}
+}
+
+// Records each flush of a buffered writer
+type flushRecorder struct {
+	bytes.Buffer
+	flushed []string // What had been written at each flush
+}
+
+func (f *flushRecorder) Flush() error {
+	f.flushed = append(f.flushed, f.String())
+	return nil
+}
+
+// Test NDJSON with -R follows each directory with its subdirectories' entries,
+// writing every directory out as soon as it is read
+func TestStreamNDJSON_Recursive(t *testing.T) {
+	tempDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(tempDir, "a", "deep"), 0o755); err != nil {
+		t.Fatalf("Failed to create test directories: %v", err)
+	}
+	for _, name := range []string{"a/deep/x.txt", "b.txt"} {
+		if err := os.WriteFile(filepath.Join(tempDir, name), nil, 0o644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+
+	var out flushRecorder
+	if errs, err := internal.StreamNDJSON(&out, tempDir, internal.ParseFlag("-R")); err != nil || len(errs) > 0 {
+		t.Fatalf("StreamNDJSON failed: %v, %v", err, errs)
+	}
+
+	var names []string
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var entry internal.JSONEntry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("Line is not valid JSON: %v", err)
+		}
+		names = append(names, entry.Name)
+	}
+	if strings.Join(names, " ") != "a deep x.txt b.txt" {
+		t.Errorf("Expected entries in tree order, Got %v", names)
+	}
+
+	// The deepest directory is written before its parents are finished
+	if len(out.flushed) != 3 || strings.Count(out.flushed[0], "\n") != 3 {
+		t.Errorf("Expected a flush per directory, the first after 3 entries, Got %q", out.flushed)
+	}
+}
@@ -0,0 +1,163 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/DavJesse/ls-clone/ls"
+)
+
+// Test file operands come first, then directory contents, and bad paths are reported
+func TestList_Operands(t *testing.T) {
+	tempDir := makeHiddenFixture(t)
+	file := filepath.Join(tempDir, "shown.txt")
+	missing := filepath.Join(tempDir, "missing")
+
+	entries, err := ls.List(context.Background(), []string{tempDir, missing, file}, ls.Options{AlmostAll: true})
+
+	var operandErr *ls.OperandError
+	if !errors.As(err, &operandErr) || operandErr.Path != missing {
+		t.Errorf("Expected an OperandError for %v, Got %v", missing, err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name)
+	}
+	expect := []string{file, ".hidden", "shown.txt"}
+	if len(names) != len(expect) || names[0] != expect[0] || names[1] != expect[1] || names[2] != expect[2] {
+		t.Errorf("Expected %v, Got %v", expect, names)
+	}
+	if entries[1].Dir != tempDir {
+		t.Errorf("Expected Dir %v, Got %v", tempDir, entries[1].Dir)
+	}
+}
+
+// A MapFS whose directory top/locked can't be opened
+type lockedFS struct {
+	fstest.MapFS
+}
+
+func (l lockedFS) Open(name string) (fs.File, error) {
+	if name == "top/locked" {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrPermission}
+	}
+	return l.MapFS.Open(name)
+}
+
+// Test an unreadable subdirectory is reported on its own, and the rest of the tree still returned
+func TestList_UnreadableSubdirectory(t *testing.T) {
+	fsys := lockedFS{fstest.MapFS{
+		"top/a.txt":        {},
+		"top/locked/b.txt": {},
+		"top/open/c.txt":   {},
+		"top/empty":        {Mode: fs.ModeDir | 0o755},
+	}}
+
+	entries, err := ls.List(context.Background(), []string{"top"}, ls.Options{Recursive: true, FS: fsys})
+
+	var operandErr *ls.OperandError
+	if !errors.As(err, &operandErr) || operandErr.Path != "top/locked" || err.Error() != "cannot open directory 'top/locked': Permission denied" {
+		t.Errorf("Expected an error for top/locked only, Got %v", err)
+	}
+	if len(entries) != 4 {
+		t.Fatalf("Expected the 4 entries of top, Got %v", entries)
+	}
+	if entries[1].Name != "empty" || entries[1].Children == nil || entries[2].Name != "locked" || entries[2].Children != nil {
+		t.Errorf("Expected empty read and locked unread, Got %+v and %+v", entries[1], entries[2])
+	}
+	if entries[3].Name != "open" || len(entries[3].Children) != 1 || entries[3].Children[0].Name != "c.txt" {
+		t.Errorf("Expected open's children to be read, Got %+v", entries[3])
+	}
+}
+
+// Test a cancelled context stops the listing
+func TestList_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := ls.List(ctx, []string{makeHiddenFixture(t)}, ls.Options{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, Got %v", err)
+	}
+	if err := ls.Walk(ctx, makeHiddenFixture(t), ls.Options{}, func(ls.Entry) error { return nil }); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, Got %v", err)
+	}
+}
+
+// Test unknown sort orders and formats are rejected
+func TestList_InvalidOptions(t *testing.T) {
+	if _, err := ls.List(context.Background(), nil, ls.Options{Sort: "random"}); err == nil {
+		t.Errorf("Expected error for unknown sort, Got nil")
+	}
+	if _, err := ls.List(context.Background(), nil, ls.Options{Ignore: []string{"[a-"}}); err == nil {
+		t.Errorf("Expected error for bad pattern, Got nil")
+	}
+	if err := ls.Render(&bytes.Buffer{}, nil, "xml"); err == nil {
+		t.Errorf("Expected error for unknown format, Got nil")
+	}
+}
+
+// Test recursive listings render one section per directory, including empty ones
+func TestRender_RecursiveSections(t *testing.T) {
+	tempDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(tempDir, "a", "empty"), 0o755); err != nil {
+		t.Fatalf("Failed to create test directories: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, "a", "file"), nil, 0o644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	entries, err := ls.List(context.Background(), []string{tempDir}, ls.Options{Recursive: true})
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := ls.Render(&buf, entries, ls.FormatShort); err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+
+	a := filepath.Join(tempDir, "a")
+	expect := "a/\n\n" + a + ":\nempty/\nfile\n\n" + filepath.Join(a, "empty") + ":\n"
+	if buf.String() != expect {
+		t.Errorf("Expected %q, Got %q", expect, buf.String())
+	}
+}
+
+// Test a command line is read into Options, and its mistakes can be told apart with errors.As and errors.Is
+func TestParseArgs_Library(t *testing.T) {
+	opts, paths, err := ls.ParseArgs([]string{"-lrt", "--group-directories-first", "--hide=*.o", "src"})
+	if err != nil {
+		t.Fatalf("ParseArgs failed: %v", err)
+	}
+	if opts.Sort != ls.SortTime || !opts.Reverse || !opts.GroupDirectoriesFirst || len(opts.Hide) != 1 || len(paths) != 1 || paths[0] != "src" {
+		t.Errorf("Unexpected options: %+v, %v", opts, paths)
+	}
+	if opts, _, _ := ls.ParseArgs([]string{"-U"}); opts.Sort != ls.SortNone {
+		t.Errorf("Expected SortNone for -U, Got %q", opts.Sort)
+	}
+
+	testCases := []struct {
+		args   []string
+		option string
+		err    error
+	}{
+		{[]string{"-m"}, "-m", ls.ErrInvalidOption},
+		{[]string{"-I"}, "-I", ls.ErrMissingArgument},
+		{[]string{"--sort=colour"}, "--sort", ls.ErrInvalidArgument},
+	}
+	for _, tc := range testCases {
+		_, _, err := ls.ParseArgs(tc.args)
+
+		var optionErr *ls.OptionError
+		if !errors.As(err, &optionErr) || optionErr.Option != tc.option || !errors.Is(err, tc.err) {
+			t.Errorf("ParseArgs(%q) = %v; want an OptionError for %v wrapping %v", tc.args, err, tc.option, tc.err)
+		}
+	}
+}
@@ -1 +1,306 @@
 package tests
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+
+	internal "github.com/DavJesse/ls-clone/internal/ls"
+)
+
+// Test inode numbers match those reported by the system
+func TestRetrieveMetaData_Inode(t *testing.T) {
+	tempDir := t.TempDir()
+	filePath := filepath.Join(tempDir, "file.txt")
+	if err := os.WriteFile(filePath, []byte("content"), 0o644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	info, err := os.Lstat(filePath)
+	if err != nil {
+		t.Fatalf("Failed to stat test file: %v", err)
+	}
+	expect := info.Sys().(*syscall.Stat_t).Ino
+
+	result, err := internal.RetrieveMetaData(filePath)
+	if err != nil {
+		t.Fatalf("RetrieveMetaData failed: %v", err)
+	}
+	if result.Inode != uint64(expect) {
+		t.Errorf("Expected inode %d, Got %d", expect, result.Inode)
+	}
+}
+
+// Test hard-linked entries are grouped, including those in subdirectories
+func TestHardLinkGroups(t *testing.T) {
+	tempDir := t.TempDir()
+	original := filepath.Join(tempDir, "a.txt")
+	if err := os.WriteFile(original, []byte("content"), 0o644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, "lonely.txt"), []byte("content"), 0o644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	if err := os.Mkdir(filepath.Join(tempDir, "sub"), 0o755); err != nil {
+		t.Fatalf("Failed to create test directory: %v", err)
+	}
+	if err := os.Link(original, filepath.Join(tempDir, "sub", "b.txt")); err != nil {
+		t.Fatalf("Failed to create hard link: %v", err)
+	}
+
+	groups := internal.HardLinkGroups(retrieveEntries(t, tempDir, internal.Options{Recursive: true}))
+	if len(groups) != 1 {
+		t.Fatalf("Expected 1 hard link group, Got %d", len(groups))
+	}
+	if len(groups[0]) != 2 || groups[0][0].Name != "a.txt" || groups[0][1].Name != "b.txt" {
+		t.Errorf("Expected [a.txt b.txt], Got %v", groups[0])
+	}
+}
+
+// Test inode numbers lead names and details with -i
+func TestEntryName_Inode(t *testing.T) {
+	file := internal.FileInfo{Name: "file.txt"}
+	file.Meta.Inode = 42
+	file.Meta.Mode = 0o644
+
+	opts := internal.ParseFlag("-i")
+	if result := internal.EntryName(file, opts); result != "42 file.txt" {
+		t.Errorf("Expected '42 file.txt', Got '%v'", result)
+	}
+	if result := internal.EntryDetail(file, opts); result != "42 "+internal.FormatDetail(file, opts) {
+		t.Errorf("Expected inode before detail, Got '%v'", result)
+	}
+
+	opts = internal.ParseFlag("-l")
+	if result := internal.EntryName(file, opts); result != "file.txt" {
+		t.Errorf("Expected 'file.txt', Got '%v'", result)
+	}
+}
+
+// Test -i pads inodes to the widest of each listing, in short listings and each level of a tree
+func TestRenderEntries_InodeWidth(t *testing.T) {
+	files := []internal.FileInfo{{Name: "a"}, {Name: "b"}}
+	files[0].Meta.Inode = 7
+	files[1].Meta.Inode = 1234
+	files[1].Meta.Mode = fs.ModeDir | 0o755
+	files[1].RecursiveList = []internal.FileInfo{{Name: "c"}}
+	files[1].RecursiveList[0].Meta.Inode = 56
+	opts := internal.Options{Inode: true, NoColor: true}
+
+	var buf bytes.Buffer
+	if err := internal.RenderEntries(&buf, files, opts); err != nil {
+		t.Fatalf("RenderEntries failed: %v", err)
+	}
+	if expect := "   7 a\n1234 b/\n"; buf.String() != expect {
+		t.Errorf("Expected %q, Got %q", expect, buf.String())
+	}
+
+	buf.Reset()
+	if err := internal.RenderTree(&buf, ".", files, opts, internal.ASCIIConnectors); err != nil {
+		t.Fatalf("RenderTree failed: %v", err)
+	}
+	if expect := ".\n|--    7 a\n`-- 1234 b/\n    `-- 56 c\n"; !strings.HasPrefix(buf.String(), expect) {
+		t.Errorf("Expected %q, Got %q", expect, buf.String())
+	}
+}
+
+// Builds a directory holding a dotfile and a regular file
+func makeHiddenFixture(t *testing.T) string {
+	tempDir := t.TempDir()
+	for _, name := range []string{".hidden", "shown.txt"} {
+		if err := os.WriteFile(filepath.Join(tempDir, name), nil, 0o644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+	return tempDir
+}
+
+// Collects the names of a listing
+// Lists a directory, failing the test if any part of it can't be read
+func retrieveEntries(tb testing.TB, path string, opts internal.Options) []internal.FileInfo {
+	tb.Helper()
+	files, errs := internal.RetrieveEntries(path, opts)
+	if len(errs) > 0 {
+		tb.Fatalf("RetrieveEntries(%v) failed: %v", path, errs)
+	}
+	return files
+}
+
+func entryNames(files []internal.FileInfo) []string {
+	var names []string
+	for i := range files {
+		names = append(names, files[i].Name)
+	}
+	return names
+}
+
+// Test -a, -A and the default listing show the right entries
+func TestRetrieveEntries_HiddenModes(t *testing.T) {
+	tempDir := makeHiddenFixture(t)
+
+	testCases := []struct {
+		flag   string
+		expect string
+	}{
+		{"-l", "[shown.txt]"},
+		{"-A", "[.hidden shown.txt]"},
+		{"-a", "[. .. .hidden shown.txt]"},
+	}
+
+	for _, tc := range testCases {
+		result := fmt.Sprint(entryNames(retrieveEntries(t, tempDir, internal.ParseFlag(tc.flag))))
+		if result != tc.expect {
+			t.Errorf("RetrieveEntries(%v) = %v; want %v", tc.flag, result, tc.expect)
+		}
+	}
+}
+
+// Test '.' and '..' carry their own metadata
+func TestRetrieveEntries_DotEntries(t *testing.T) {
+	tempDir := makeHiddenFixture(t)
+
+	files := retrieveEntries(t, tempDir, internal.ParseFlag("-a"))
+	self, _ := internal.RetrieveMetaData(tempDir)
+	parent, _ := internal.RetrieveMetaData(filepath.Dir(tempDir))
+
+	if files[0].Meta.Inode != self.Inode || !files[0].Meta.Mode.IsDir() {
+		t.Errorf("Expected '.' to have inode %d, Got %d", self.Inode, files[0].Meta.Inode)
+	}
+	if files[1].Meta.Inode != parent.Inode {
+		t.Errorf("Expected '..' to have inode %d, Got %d", parent.Inode, files[1].Meta.Inode)
+	}
+}
+
+// Test a custom hide function replaces the dotfile rule, and -A overrides it
+func TestRetrieveEntries_CustomHide(t *testing.T) {
+	tempDir := makeHiddenFixture(t)
+
+	opts := internal.Options{Hide: func(dir, name string, isDir bool) bool {
+		return strings.HasSuffix(name, ".txt")
+	}}
+	if result := fmt.Sprint(entryNames(retrieveEntries(t, tempDir, opts))); result != "[.hidden]" {
+		t.Errorf("Expected [.hidden], Got %v", result)
+	}
+
+	opts.AlmostAll = true
+	if result := fmt.Sprint(entryNames(retrieveEntries(t, tempDir, opts))); result != "[.hidden shown.txt]" {
+		t.Errorf("Expected [.hidden shown.txt], Got %v", result)
+	}
+}
+
+// Test --ignore always applies, while --hide gives way to -a and -A
+func TestRetrieveEntries_IgnoreAndHide(t *testing.T) {
+	tempDir := t.TempDir()
+	for _, name := range []string{"keep.go", "notes.md", "backup.go~", "build.log"} {
+		if err := os.WriteFile(filepath.Join(tempDir, name), nil, 0o644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+
+	testCases := []struct {
+		args   []string
+		expect string
+	}{
+		{[]string{"-I", "*.log"}, "[backup.go~ keep.go notes.md]"},
+		{[]string{"-I*.log", "-B"}, "[keep.go notes.md]"},
+		{[]string{"--hide=*.md", "--ignore-backups"}, "[build.log keep.go]"},
+		{[]string{"-A", "--hide=*.md", "--ignore=*.log"}, "[backup.go~ keep.go notes.md]"},
+	}
+
+	for _, tc := range testCases {
+		opts, _, err := internal.ParseArgs(tc.args)
+		if err != nil {
+			t.Fatalf("ParseArgs(%q) failed: %v", tc.args, err)
+		}
+
+		result := fmt.Sprint(entryNames(retrieveEntries(t, tempDir, opts)))
+		if result != tc.expect {
+			t.Errorf("RetrieveEntries(%q) = %v; want %v", tc.args, result, tc.expect)
+		}
+	}
+}
+
+// Test ignored directories are not descended into
+func TestRetrieveEntries_IgnoreSkipsSubtree(t *testing.T) {
+	tempDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(tempDir, "node_modules", "pkg"), 0o755); err != nil {
+		t.Fatalf("Failed to create test directories: %v", err)
+	}
+
+	// An unreadable directory inside would be fatal if it were visited
+	if err := os.Chmod(filepath.Join(tempDir, "node_modules"), 0o000); err != nil {
+		t.Fatalf("Failed to change mode: %v", err)
+	}
+	defer os.Chmod(filepath.Join(tempDir, "node_modules"), 0o755)
+
+	files := retrieveEntries(t, tempDir, internal.Options{Ignore: []string{"node_modules"}})
+	if len(files) != 0 {
+		t.Errorf("Expected empty listing, Got %v", entryNames(files))
+	}
+}
+
+// Test -d lists a directory operand itself, named as given
+func TestRetrieveOperand_Directory(t *testing.T) {
+	tempDir := makeHiddenFixture(t)
+	operand := tempDir + "/"
+
+	file, err := internal.RetrieveOperand(operand)
+	if err != nil {
+		t.Fatalf("RetrieveOperand failed: %v", err)
+	}
+
+	self, _ := internal.RetrieveMetaData(tempDir)
+	if file.Name != operand || !file.Meta.Mode.IsDir() || file.Meta.Inode != self.Inode || len(file.RecursiveList) != 0 {
+		t.Errorf("Unexpected operand entry: %+v", file)
+	}
+
+	if _, err := internal.RetrieveOperand(filepath.Join(tempDir, "missing")); err == nil {
+		t.Errorf("Expected error for missing operand, Got nil")
+	}
+}
+
+// Test a regular file operand is listed as a single entry
+func TestRetrieveEntries_FileOperand(t *testing.T) {
+	tempDir := makeHiddenFixture(t)
+	operand := filepath.Join(tempDir, "shown.txt")
+
+	files := retrieveEntries(t, operand, internal.Options{})
+	if len(files) != 1 || files[0].Name != operand || files[0].Meta.Mode.IsDir() {
+		t.Errorf("Expected single entry for %v, Got %+v", operand, files)
+	}
+}
+
+// Test -U keeps directory order and -f also shows dot entries without color
+func TestParseFlag_Unsorted(t *testing.T) {
+	opts := internal.ParseFlag("-U")
+	if !opts.Unsorted || opts.All || opts.NoColor {
+		t.Errorf("Expected -U to set only Unsorted, Got %+v", opts)
+	}
+
+	opts = internal.ParseFlag("-f")
+	if !opts.Unsorted || !opts.All || !opts.NoColor {
+		t.Errorf("Expected -f to imply -a -U without color, Got %+v", opts)
+	}
+
+	dir := internal.FileInfo{Name: "sub"}
+	dir.Meta.Mode = os.ModeDir | 0o755
+	if result := internal.FormatName(dir, opts); result != "sub/" {
+		t.Errorf("Expected 'sub/', Got %q", result)
+	}
+}
+
+// Test -U keeps file operands in command-line order
+func TestSplitOperands_Unsorted(t *testing.T) {
+	tempDir := makeHiddenFixture(t)
+	operands := []string{filepath.Join(tempDir, "shown.txt"), filepath.Join(tempDir, ".hidden")}
+
+	files, _, _ := internal.SplitOperands(operands, internal.ParseFlag("-U"))
+	if result := fmt.Sprint(entryNames(files)); result != fmt.Sprint(operands) {
+		t.Errorf("Expected %v, Got %v", operands, result)
+	}
+}
@@ -1,11 +1,14 @@
 package tests
 
 import (
+	"errors"
+	"io/fs"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
-	internal "my-ls/internal/ls"
+	internal "github.com/DavJesse/ls-clone/internal/ls"
 )
 
 // Test non-empty paths
@@ -35,10 +38,12 @@ func TestIsValidPath_SingleCharacterPath(t *testing.T) {
 }
 
 // Test paths with only white spaces
+// Spaces are legal in Linux file names
 func TestIsValidPath_WhitespaceOnly(t *testing.T) {
-	result, _ := internal.IsValidPath("   ")
-	if result {
-		t.Errorf("Expected false; Got true")
+	result, err := internal.IsValidPath("   ")
+	if !result {
+		t.Errorf("Expected true; Got false")
+		t.Errorf("Reason: %v", err)
 	}
 }
 
@@ -94,15 +99,21 @@ func TestIsValidPath_CaseSensitivePath(t *testing.T) {
 }
 
 // Test leasing and trailing whitespaces
+// Only empty paths and paths holding NUL are illegal on Linux
 func TestIsValidPath_LeadingAndTrailingWhitespacePath(t *testing.T) {
 	testCases := []struct {
 		input    string
 		expected bool
 	}{
-		{"  path  ", false},
-		{"  ", false},
-		{" ", false},
-		{"\t\n", false},
+		{"  path  ", true},
+		{"  ", true},
+		{" ", true},
+		{"\t\n", true},
+		{"My Documents", true},
+		{"back\\slash", true},
+		{"\x1b[31mred", true},
+		{"-leading", true},
+		{"nul\x00byte", false},
 		{"", false},
 	}
 
@@ -114,17 +125,78 @@ func TestIsValidPath_LeadingAndTrailingWhitespacePath(t *testing.T) {
 	}
 }
 
+// Test operands are checked against the filesystem, with errors worded as ls does
+func TestSplitOperands_AccessErrors(t *testing.T) {
+	tempDir := t.TempDir()
+	spaced := filepath.Join(tempDir, "My Documents")
+	if err := os.Mkdir(spaced, 0o755); err != nil {
+		t.Fatalf("Failed to create test directory: %v", err)
+	}
+	missing := filepath.Join(tempDir, "missing")
+	tooLong := filepath.Join(tempDir, strings.Repeat("a", 300))
+
+	_, dirs, errs := internal.SplitOperands([]string{missing, spaced, tooLong}, internal.Options{})
+	if len(dirs) != 1 || dirs[0] != spaced {
+		t.Errorf("Expected [%v], Got %v", spaced, dirs)
+	}
+	if len(errs) != 2 {
+		t.Fatalf("Expected 2 errors, Got %v", errs)
+	}
+
+	expect := "cannot access '" + missing + "': No such file or directory"
+	if errs[0].Error() != expect {
+		t.Errorf("Expected %q, Got %q", expect, errs[0].Error())
+	}
+	if !errors.Is(errs[0], fs.ErrNotExist) {
+		t.Errorf("Expected error to wrap fs.ErrNotExist")
+	}
+	if !strings.HasSuffix(errs[1].Error(), ": File name too long") {
+		t.Errorf("Expected 'File name too long', Got %q", errs[1].Error())
+	}
+}
+
+// Test unreadable directory operands are reported
+func TestSplitOperands_PermissionDenied(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("root can read any directory")
+	}
+
+	locked := filepath.Join(t.TempDir(), "locked")
+	if err := os.Mkdir(locked, 0o000); err != nil {
+		t.Fatalf("Failed to create test directory: %v", err)
+	}
+	defer os.Chmod(locked, 0o755)
+
+	_, _, errs := internal.SplitOperands([]string{locked}, internal.Options{})
+	expect := "cannot open directory '" + locked + "': Permission denied"
+	if len(errs) != 1 || errs[0].Error() != expect {
+		t.Errorf("Expected %q, Got %v", expect, errs)
+	}
+}
+
 // Test handling of current directory
 func TestRetrieveFileInfo_CurrentDir(t *testing.T) {
-	var expect []internal.FileInfo
-	var result []internal.FileInfo
+	var expect []internal.LegacyFileInfo
+	var result []internal.LegacyFileInfo
 	var point int
 
 	result = internal.RetrieveFileInfo(".", false)
-	expect = []internal.FileInfo{
+	expect = []internal.LegacyFileInfo{
+		{DocName: "archive_test.go"},
+		{DocName: "backend_test.go"},
+		{DocName: "config_test.go"},
+		{DocName: "csv_test.go"},
+		{DocName: "display_test.go"},
 		{DocName: "flag_test.go"},
+		{DocName: "gitignore_test.go"},
+		{DocName: "golden_test.go"},
+		{DocName: "json_test.go"},
+		{DocName: "library_test.go"},
 		{DocName: "ls_test.go"},
 		{DocName: "path_test.go"},
+		{DocName: "quoting_test.go"},
+		{DocName: "recursive_test.go"},
+		{DocName: "run_test.go"},
 		{DocName: "sort_args_test.go"},
 	}
 
@@ -140,17 +212,18 @@ func TestRetrieveFileInfo_CurrentDir(t *testing.T) {
 
 // Test handling of non current directory
 func TestRetrieveFileInfo_NonCurrentDir(t *testing.T) {
-	var expect []internal.FileInfo
-	var result []internal.FileInfo
+	var expect []internal.LegacyFileInfo
+	var result []internal.LegacyFileInfo
 	var point int
 
 	result = internal.RetrieveFileInfo("../", false)
-	expect = []internal.FileInfo{
+	expect = []internal.LegacyFileInfo{
 		{DocName: "\033[01;34mcmd\033[0m/"},
 		{DocName: "\033[01;32mcommit.sh\033[0m*"},
 		{DocName: "go.mod"},
 		{DocName: "\033[01;34minternal\033[0m/"},
 		{DocName: "LICENSE"},
+		{DocName: "\033[01;34mls\033[0m/"},
 		{DocName: "\033[01;32mpush_both.sh\033[0m*"},
 		{DocName: "README.md"},
 		{DocName: "\033[01;32mrun_my_ls.sh\033[0m*"},
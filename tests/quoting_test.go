@@ -0,0 +1,83 @@
+package tests
+
+import (
+	"testing"
+
+	internal "github.com/DavJesse/ls-clone/internal/ls"
+)
+
+// Test every quoting style against names with special characters
+func TestQuoteName_Styles(t *testing.T) {
+	testCases := []struct {
+		name   string
+		style  string
+		expect string
+	}{
+		{"plain", "literal", "plain"},
+		{"a\nb", "literal", "a\nb"},
+		{"plain", "shell", "plain"},
+		{"a b", "shell", "'a b'"},
+		{"it's", "shell", "\"it's\""},
+		{"it's $x", "shell", "'it'\\''s $x'"},
+		{"#tag", "shell", "'#tag'"},
+		{"a#b", "shell", "a#b"},
+		{"", "shell", "''"},
+		{"plain", "shell-always", "'plain'"},
+		{"plain", "shell-escape", "plain"},
+		{"a\nb", "shell-escape", "'a'$'\\n''b'"},
+		{"\033[31mred", "shell-escape", "$'\\033''[31mred'"},
+		{"a\nb", "c", "\"a\\nb\""},
+		{"say \"hi\"", "c", "\"say \\\"hi\\\"\""},
+		{"a b\tc", "escape", "a\\ b\\tc"},
+		{"back\\slash", "escape", "back\\\\slash"},
+		{"\xff", "escape", "\\377"},
+		{"こんにちは", "escape", "こんにちは"},
+	}
+
+	for _, tc := range testCases {
+		if result := internal.QuoteName(tc.name, tc.style, false); result != tc.expect {
+			t.Errorf("QuoteName(%q, %v) = %q; want %q", tc.name, tc.style, result, tc.expect)
+		}
+	}
+}
+
+// Test -q replaces nonprintables where escaping doesn't apply
+func TestQuoteName_HideControl(t *testing.T) {
+	testCases := []struct {
+		style  string
+		expect string
+	}{
+		{"literal", "a?b"},
+		{"shell", "'a?b'"},
+		{"c", "\"a\\nb\""},
+	}
+
+	for _, tc := range testCases {
+		if result := internal.QuoteName("a\nb", tc.style, true); result != tc.expect {
+			t.Errorf("QuoteName(%v, -q) = %q; want %q", tc.style, result, tc.expect)
+		}
+	}
+}
+
+// Test quoting flags and --quoting-style parse into options
+func TestParseArgs_Quoting(t *testing.T) {
+	testCases := []struct {
+		args   []string
+		expect string
+	}{
+		{[]string{"-b"}, "escape"},
+		{[]string{"-Q"}, "c"},
+		{[]string{"--quoting-style=shell-always"}, "shell-always"},
+	}
+
+	for _, tc := range testCases {
+		opts, _, err := internal.ParseArgs(tc.args)
+		if err != nil || opts.QuotingStyle != tc.expect {
+			t.Errorf("ParseArgs(%q) = %q, %v; want %q", tc.args, opts.QuotingStyle, err, tc.expect)
+		}
+	}
+
+	if _, _, err := internal.ParseArgs([]string{"--quoting-style=fancy"}); err == nil {
+		t.Errorf("Expected error for unknown quoting style, Got nil")
+	}
+}
@@ -0,0 +1,164 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	internal "github.com/DavJesse/ls-clone/internal/ls"
+)
+
+// Generates a tree 'depth' levels deep, each directory holding
+// 'width' subdirectories and 'files' regular files
+func makeGeneratedTree(tb testing.TB, depth, width, files int) string {
+	root := tb.TempDir()
+
+	var build func(dir string, level int)
+	build = func(dir string, level int) {
+		for i := 0; i < files; i++ {
+			if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("file%02d.txt", i)), nil, 0o644); err != nil {
+				tb.Fatalf("Failed to create test file: %v", err)
+			}
+		}
+		if level == depth {
+			return
+		}
+		for i := 0; i < width; i++ {
+			sub := filepath.Join(dir, fmt.Sprintf("Dir%02d", i))
+			if err := os.Mkdir(sub, 0o755); err != nil {
+				tb.Fatalf("Failed to create test directory: %v", err)
+			}
+			build(sub, level+1)
+		}
+	}
+	build(root, 0)
+	return root
+}
+
+// Flattens a recursive listing into paths, in listing order
+func flattenPaths(files []internal.FileInfo) []string {
+	var paths []string
+	for i := range files {
+		paths = append(paths, files[i].Path)
+		paths = append(paths, flattenPaths(files[i].RecursiveList)...)
+	}
+	return paths
+}
+
+// Test parallel scans give the same listing, in the same order, as a sequential one
+func TestScanner_DeterministicOrder(t *testing.T) {
+	root := makeGeneratedTree(t, 3, 4, 3)
+
+	sequential := flattenPaths(retrieveEntries(t, root, internal.Options{Recursive: true, Jobs: 1}))
+	for _, jobs := range []int{2, 8, 64} {
+		parallel := flattenPaths(retrieveEntries(t, root, internal.Options{Recursive: true, Jobs: jobs}))
+		if strings.Join(parallel, "\n") != strings.Join(sequential, "\n") {
+			t.Errorf("Listing with %d jobs differs from sequential listing", jobs)
+		}
+	}
+
+	// 4 + 16 + 64 directories, each level holding 3 files
+	if len(sequential) != 84+3*85 {
+		t.Errorf("Expected %d entries, Got %d", 84+3*85, len(sequential))
+	}
+}
+
+// Test subdirectories are only read with -R
+func TestRetrieveEntries_NotRecursive(t *testing.T) {
+	root := makeGeneratedTree(t, 2, 2, 1)
+
+	files := retrieveEntries(t, root, internal.Options{})
+	if len(flattenPaths(files)) != 3 {
+		t.Errorf("Expected only the top level, Got %v", flattenPaths(files))
+	}
+}
+
+// Test --jobs takes a positive number
+func TestParseArgs_Jobs(t *testing.T) {
+	opts, _, err := internal.ParseArgs([]string{"-R", "--jobs=16"})
+	if err != nil || opts.Jobs != 16 {
+		t.Errorf("Unexpected result: %+v, %v", opts, err)
+	}
+
+	for _, arg := range []string{"--jobs=0", "--jobs=many"} {
+		if _, _, err := internal.ParseArgs([]string{arg}); err == nil {
+			t.Errorf("ParseArgs(%v): Expected error, Got nil", arg)
+		}
+	}
+}
+
+// Test unreadable subdirectories are reported in tree order, and -R lists the rest without them
+func TestRetrieveEntries_UnreadableSubdirectory(t *testing.T) {
+	fsys := lockedFS{fstest.MapFS{
+		"top/a.txt":        {},
+		"top/locked/b.txt": {},
+		"top/open/c.txt":   {},
+		"top/empty":        {Mode: fs.ModeDir | 0o755},
+	}}
+	opts := internal.Options{FS: fsys, Recursive: true, Jobs: 4, NoColor: true}
+
+	files, errs := internal.RetrieveEntries("top", opts)
+	if len(errs) != 1 || errs[0].Error() != "cannot open directory 'top/locked': Permission denied" {
+		t.Fatalf("Expected an error for top/locked only, Got %v", errs)
+	}
+	if result := fmt.Sprint(flattenPaths(files)); result != "[top/a.txt top/empty top/locked top/open top/open/c.txt]" {
+		t.Errorf("Expected the rest of the tree, Got %v", result)
+	}
+
+	var buf bytes.Buffer
+	if err := internal.RenderDirectory(&buf, "top", files, opts, true); err != nil {
+		t.Fatalf("RenderDirectory failed: %v", err)
+	}
+	expect := "top:\na.txt\nempty/\nlocked/\nopen/\n\ntop/empty:\n\ntop/open:\nc.txt\n"
+	if buf.String() != expect {
+		t.Errorf("Expected %q, Got %q", expect, buf.String())
+	}
+
+	// The same is reported while NDJSON is streamed, and the listing carries on past it
+	buf.Reset()
+	errs, err := internal.StreamNDJSON(&buf, "top", opts)
+	if err != nil || len(errs) != 1 || errs[0].Error() != "cannot open directory 'top/locked': Permission denied" {
+		t.Errorf("Expected an error for top/locked only, Got %v, %v", err, errs)
+	}
+	if lines := strings.Count(buf.String(), "\n"); lines != 5 {
+		t.Errorf("Expected 5 entries, Got %d: %q", lines, buf.String())
+	}
+}
+
+// Test a cancelled scan reads the directory itself, but none of its subdirectories
+func TestScanner_Cancelled(t *testing.T) {
+	root := makeGeneratedTree(t, 2, 3, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	files, errs := internal.NewScanner(internal.Options{Recursive: true}).ScanContext(ctx, root)
+	if len(errs) > 0 || len(files) != 4 {
+		t.Fatalf("Expected the 4 entries of the root, Got %v, %v", entryNames(files), errs)
+	}
+	for i := range files {
+		if files[i].RecursiveList != nil {
+			t.Errorf("Expected %v to be left unread, Got %v", files[i].Name, entryNames(files[i].RecursiveList))
+		}
+	}
+}
+
+// Reads the same generated tree with different worker counts
+func benchmarkScan(b *testing.B, jobs int) {
+	root := makeGeneratedTree(b, 3, 6, 20)
+	opts := internal.Options{Recursive: true, Jobs: jobs}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		retrieveEntries(b, root, opts)
+	}
+}
+
+func BenchmarkScan_Jobs1(b *testing.B)  { benchmarkScan(b, 1) }
+func BenchmarkScan_Jobs4(b *testing.B)  { benchmarkScan(b, 4) }
+func BenchmarkScan_Jobs16(b *testing.B) { benchmarkScan(b, 16) }
@@ -0,0 +1,102 @@
+package tests
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	internal "github.com/DavJesse/ls-clone/internal/ls"
+)
+
+// Serves environment variables from a map, in place of os.Getenv
+func mapEnv(vars map[string]string) func(string) string {
+	return func(name string) string {
+		return vars[name]
+	}
+}
+
+// Test a listing is written to stdout, with exit status 0
+func TestRun_Listing(t *testing.T) {
+	tempDir := makeTreeFixture(t)
+
+	var stdout, stderr bytes.Buffer
+	status := internal.Run([]string{tempDir}, mapEnv(nil), &stdout, &stderr)
+
+	if status != 0 || stderr.Len() != 0 {
+		t.Errorf("Expected status 0 and no errors, Got %d, %q", status, stderr.String())
+	}
+	if stdout.String() != "\033[01;34ma\033[0m/\ntop.txt\n" {
+		t.Errorf("Unexpected output: %q", stdout.String())
+	}
+}
+
+// Test bad flags and missing operands fail with status 2, reported on stderr as coreutils reports them
+func TestRun_ExitStatus(t *testing.T) {
+	tempDir := makeTreeFixture(t)
+	missing := filepath.Join(tempDir, "nope")
+
+	testCases := []struct {
+		args   []string
+		status int
+		stderr string
+	}{
+		{[]string{"-m"}, 2, "my-ls: invalid option -- 'm'\nTry 'my-ls --help' for more information.\n"},
+		{[]string{"-lI"}, 2, "my-ls: option requires an argument -- 'I'\nTry 'my-ls --help' for more information.\n"},
+		{[]string{missing, tempDir}, 2, "my-ls: cannot access '" + missing + "': No such file or directory\n"},
+	}
+
+	for _, tc := range testCases {
+		var stdout, stderr bytes.Buffer
+		status := internal.Run(tc.args, mapEnv(nil), &stdout, &stderr)
+		if status != tc.status || stderr.String() != tc.stderr {
+			t.Errorf("Run(%v) = %d, %q; want %d, %q", tc.args, status, stderr.String(), tc.status, tc.stderr)
+		}
+	}
+}
+
+// Test the locale is read from the environment given, not from the process
+func TestRun_Env(t *testing.T) {
+	tempDir := makeTreeFixture(t)
+	t.Setenv("LC_ALL", "C.UTF-8")
+
+	var ascii, unicode bytes.Buffer
+	internal.Run([]string{"--tree", tempDir}, mapEnv(nil), &ascii, os.Stderr)
+	internal.Run([]string{"--tree", tempDir}, mapEnv(map[string]string{"LANG": "en_US.UTF-8"}), &unicode, os.Stderr)
+
+	if !strings.Contains(ascii.String(), "`-- ") || strings.Contains(ascii.String(), "└── ") {
+		t.Errorf("Expected ASCII connectors, Got %q", ascii.String())
+	}
+	if !strings.Contains(unicode.String(), "└── ") {
+		t.Errorf("Expected Unicode connectors, Got %q", unicode.String())
+	}
+}
+
+// Test --help lists every option from the flag table, and --version names the program
+func TestRun_HelpVersion(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if status := internal.Run([]string{"--help"}, mapEnv(nil), &stdout, &stderr); status != 0 || stderr.Len() != 0 {
+		t.Fatalf("Expected status 0 and no errors, Got %d, %q", status, stderr.String())
+	}
+
+	help := stdout.String()
+	if !strings.HasPrefix(help, "Usage: my-ls [OPTION]... [FILE]...\n") {
+		t.Errorf("Unexpected usage line: %q", help)
+	}
+	for _, line := range []string{"  -a, --all                  do not ignore entries starting with .\n", "  -l                         use a long listing format\n", "      --sort=WORD            sort by WORD"} {
+		if !strings.Contains(help, line) {
+			t.Errorf("Expected help to contain %q", line)
+		}
+	}
+	for _, flag := range internal.Flags {
+		if flag.Long != "" && !strings.Contains(help, "--"+flag.Long) {
+			t.Errorf("Expected help to list --%v", flag.Long)
+		}
+	}
+
+	stdout.Reset()
+	if status := internal.Run([]string{"--version"}, mapEnv(nil), &stdout, &stderr); status != 0 || !strings.HasPrefix(stdout.String(), "my-ls ") {
+		t.Errorf("Unexpected version: %d, %q", status, stdout.String())
+	}
+}
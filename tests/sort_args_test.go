@@ -1,10 +1,12 @@
 package tests
 
 import (
+	"errors"
+	"fmt"
 	"strings"
 	"testing"
 
-	internal "my-ls/internal/ls"
+	internal "github.com/DavJesse/ls-clone/internal/ls"
 )
 
 // Test for zero-length-arguments
@@ -73,7 +75,7 @@ func TestSortArgs_TwoArgument(t *testing.T) {
 	testCases := []result{
 		{[]string{"-lRa", "directory/file"}, "-lRa", "directory/file", true},
 		{[]string{"directory/file", "-lRa"}, "", "", false},
-		{[]string{"-lRa", "directory\\file"}, "", "", false},
+		{[]string{"-lRa", "directory\\file"}, "-lRa", "directory\\file", true},
 	}
 
 	for _, tc := range testCases {
@@ -120,3 +122,217 @@ func TestSortArgs_ExcessiveArguments(t *testing.T) {
 		}
 	}
 }
+
+// Test several flag arguments, including long options, before a path
+func TestParseArgs_MultipleFlags(t *testing.T) {
+	opts, paths, err := internal.ParseArgs([]string{"-l", "-R", "--format=ndjson", "directory/file"})
+	if err != nil {
+		t.Fatalf("Expected: 'nil', Got: '%v'", err)
+	}
+
+	if opts.Long || !opts.Recursive || opts.Format != "ndjson" {
+		t.Errorf("Expected: -R and ndjson format, replacing -l, Got: %+v", opts)
+	}
+
+	if len(paths) != 1 || paths[0] != "directory/file" {
+		t.Errorf("Expected: [directory/file], Got: %v", paths)
+	}
+}
+
+// Test invalid long options and format values
+func TestParseArgs_InvalidLongOption(t *testing.T) {
+	testCases := [][]string{
+		{"--format=xml"},
+		{"--everything"},
+		{"--all=yes"},
+		{"--sort"},
+		{"directory/file", "--format=json"},
+	}
+
+	for _, tc := range testCases {
+		_, _, err := internal.ParseArgs(tc)
+		if err == nil {
+			t.Errorf("ParseArgs(%q): Expected error, Got nil", tc)
+		}
+	}
+}
+
+// Test -I needs a pattern and patterns must be valid
+func TestParseArgs_IgnorePatterns(t *testing.T) {
+	testCases := [][]string{
+		{"-I"},
+		{"-lI"},
+		{"--ignore=[a-"},
+		{"--hide=[a-"},
+	}
+
+	for _, tc := range testCases {
+		if _, _, err := internal.ParseArgs(tc); err == nil {
+			t.Errorf("ParseArgs(%q): Expected error, Got nil", tc)
+		}
+	}
+
+	opts, paths, err := internal.ParseArgs([]string{"-lI", "*.o", "dir"})
+	if err != nil || !opts.Long || len(opts.Ignore) != 1 || opts.Ignore[0] != "*.o" || paths[0] != "dir" {
+		t.Errorf("Unexpected result: %+v, %v, %v", opts, paths, err)
+	}
+}
+
+// Test '--' ends the flags, so paths may start with '-'
+func TestParseArgs_EndOfFlags(t *testing.T) {
+	opts, paths, err := internal.ParseArgs([]string{"-l", "--", "-weird name", "--"})
+	if err != nil || !opts.Long || len(paths) != 2 || paths[0] != "-weird name" || paths[1] != "--" {
+		t.Errorf("Unexpected result: %+v, %q, %v", opts, paths, err)
+	}
+}
+
+// Test every long option does what its short flag does, by full name or unambiguous prefix
+func TestParseArgs_LongOptions(t *testing.T) {
+	testCases := []struct {
+		long  []string
+		short string
+	}{
+		{[]string{"--all"}, "-a"},
+		{[]string{"--almost-all"}, "-A"},
+		{[]string{"--escape"}, "-b"},
+		{[]string{"--ignore-backups"}, "-B"},
+		{[]string{"--directory"}, "-d"},
+		{[]string{"--inode"}, "-i"},
+		{[]string{"--hide-control-chars"}, "-q"},
+		{[]string{"--quote-name"}, "-Q"},
+		{[]string{"--recursive"}, "-R"},
+		{[]string{"--reverse"}, "-r"},
+		{[]string{"--format=long"}, "-l"},
+		{[]string{"--format=verbose"}, "-l"},
+		{[]string{"--sort=time"}, "-t"},
+		{[]string{"--rev", "--rec"}, "-rR"},
+		{[]string{"--sort", "size"}, "-S"},
+		{[]string{"--so=extension"}, "-X"},
+	}
+
+	for _, tc := range testCases {
+		opts, paths, err := internal.ParseArgs(tc.long)
+		if err != nil || len(paths) != 1 || paths[0] != "." {
+			t.Errorf("ParseArgs(%q) failed: %v, %v", tc.long, paths, err)
+			continue
+		}
+		if expect := internal.ParseFlag(tc.short); fmt.Sprintf("%+v", opts) != fmt.Sprintf("%+v", expect) {
+			t.Errorf("ParseArgs(%q) = %+v; want %+v", tc.long, opts, expect)
+		}
+	}
+}
+
+// Test -l and --format=long select the same format, and the last format given wins
+func TestParseArgs_LastFormatWins(t *testing.T) {
+	testCases := []struct {
+		args   []string
+		long   bool
+		format string
+	}{
+		{[]string{"--format=json", "-l"}, true, ""},
+		{[]string{"--format=csv", "-lR"}, true, ""},
+		{[]string{"-l", "--format=json"}, false, "json"},
+		{[]string{"--format=long", "--format=tree"}, false, "tree"},
+		{[]string{"--format=tree", "--format=verbose"}, true, ""},
+	}
+
+	for _, tc := range testCases {
+		opts, _, err := internal.ParseArgs(tc.args)
+		if err != nil {
+			t.Errorf("ParseArgs(%q) failed: %v", tc.args, err)
+			continue
+		}
+		if opts.Long != tc.long || opts.Format != tc.format {
+			t.Errorf("ParseArgs(%q) = long %v, format %q; want long %v, format %q", tc.args, opts.Long, opts.Format, tc.long, tc.format)
+		}
+	}
+}
+
+// Test --color takes its argument only after '=', and the last color option wins
+func TestParseArgs_Color(t *testing.T) {
+	testCases := []struct {
+		args      []string
+		noColor   bool
+		colorAuto bool
+		paths     string
+	}{
+		{[]string{"--color", "auto"}, false, false, "[auto]"},
+		{[]string{"--color=never"}, true, false, "[.]"},
+		{[]string{"--colo=tty"}, false, true, "[.]"},
+		{[]string{"-f", "--color=always"}, false, false, "[.]"},
+		{[]string{"--color=auto", "-f"}, true, false, "[.]"},
+	}
+
+	for _, tc := range testCases {
+		opts, paths, err := internal.ParseArgs(tc.args)
+		if err != nil || opts.NoColor != tc.noColor || opts.ColorAuto != tc.colorAuto || fmt.Sprint(paths) != tc.paths {
+			t.Errorf("ParseArgs(%q) = %+v, %v, %v; want NoColor %v, ColorAuto %v, paths %v", tc.args, opts, paths, err, tc.noColor, tc.colorAuto, tc.paths)
+		}
+	}
+
+	var optionErr *internal.OptionError
+	if _, _, err := internal.ParseArgs([]string{"--color=sometimes"}); !errors.As(err, &optionErr) || !errors.Is(err, internal.ErrInvalidArgument) {
+		t.Errorf("Expected an invalid argument error, Got %v", err)
+	}
+}
+
+// Test option errors read as in coreutils and name the offending option
+func TestParseArgs_OptionErrors(t *testing.T) {
+	testCases := []struct {
+		args   []string
+		option string
+		kind   error
+		expect string
+	}{
+		{[]string{"-m"}, "-m", internal.ErrInvalidOption, "invalid option -- 'm'"},
+		{[]string{"-laz", "dir"}, "-z", internal.ErrInvalidOption, "invalid option -- 'z'"},
+		{[]string{"-lI"}, "-I", internal.ErrMissingArgument, "option requires an argument -- 'I'"},
+		{[]string{"--colour"}, "--colour", internal.ErrInvalidOption, "unrecognized option '--colour'"},
+		{[]string{"--foo=bar"}, "--foo=bar", internal.ErrInvalidOption, "unrecognized option '--foo=bar'"},
+		{[]string{"--h"}, "--h", internal.ErrAmbiguousOption, "option '--h' is ambiguous; possibilities: '--hard-links' '--human-readable' '--hide' '--hide-control-chars' '--help'"},
+		{[]string{"--rev=yes"}, "--reverse", internal.ErrUnexpectedArgument, "option '--reverse' doesn't allow an argument"},
+		{[]string{"--sort"}, "--sort", internal.ErrMissingArgument, "option '--sort' requires an argument"},
+		{[]string{"--jobs=0"}, "--jobs", internal.ErrInvalidArgument, "invalid argument '0' for '--jobs'\nexpected a positive whole number"},
+		{[]string{"-I", "[a-"}, "-I", internal.ErrInvalidArgument, "invalid argument '[a-' for '-I'\nsyntax error in pattern"},
+	}
+
+	for _, tc := range testCases {
+		_, _, err := internal.ParseArgs(tc.args)
+
+		var optionErr *internal.OptionError
+		if !errors.As(err, &optionErr) || !errors.Is(err, tc.kind) || optionErr.Option != tc.option {
+			t.Errorf("ParseArgs(%q) = %#v; want %v for %v", tc.args, err, tc.kind, tc.option)
+			continue
+		}
+		if err.Error() != tc.expect {
+			t.Errorf("ParseArgs(%q) = %q; want %q", tc.args, err.Error(), tc.expect)
+		}
+	}
+
+	_, _, err := internal.ParseArgs([]string{"--sort=random"})
+	var optionErr *internal.OptionError
+	if !errors.As(err, &optionErr) || optionErr.Value != "random" || len(optionErr.Choices) != len(internal.SortModes) {
+		t.Errorf("Expected the valid sort modes, Got %#v", err)
+	}
+}
+
+// Test a flag taking an argument takes the rest of its cluster, or the next argument
+func TestParseArgs_FlagArguments(t *testing.T) {
+	opts, paths, err := internal.ParseArgs([]string{"-aI*.o", "-I", "*.tmp", "-lIcore", "dir"})
+	if err != nil || !opts.All || !opts.Long || fmt.Sprint(opts.Ignore) != "[*.o *.tmp core]" || fmt.Sprint(paths) != "[dir]" {
+		t.Errorf("Unexpected result: %+v, %v, %v", opts, paths, err)
+	}
+}
+
+// Test --help and --version stop parsing, so later arguments don't matter
+func TestParseArgs_HelpVersion(t *testing.T) {
+	opts, paths, err := internal.ParseArgs([]string{"-l", "--help", "--bogus"})
+	if err != nil || !opts.Help || paths != nil {
+		t.Errorf("Unexpected result: %+v, %v, %v", opts, paths, err)
+	}
+
+	opts, _, err = internal.ParseArgs([]string{"--vers"})
+	if err != nil || !opts.Version {
+		t.Errorf("Unexpected result: %+v, %v", opts, err)
+	}
+}
@@ -0,0 +1,242 @@
+package tests
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	internal "github.com/DavJesse/ls-clone/internal/ls"
+)
+
+// Builds a directory whose entries differ in size, age and extension
+func makeSortFixture(t *testing.T) string {
+	tempDir := t.TempDir()
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	testFiles := []struct {
+		name string
+		size int
+		age  time.Duration
+	}{
+		{"b.txt", 4, 1 * time.Hour},
+		{"a.go", 1, 2 * time.Hour},
+		{"c.txt", 7, 3 * time.Hour},
+		{"README", 2, 4 * time.Hour},
+		{"file10", 3, 5 * time.Hour},
+		{"file2", 3, 5 * time.Hour},
+	}
+
+	for _, tf := range testFiles {
+		path := filepath.Join(tempDir, tf.name)
+		if err := os.WriteFile(path, make([]byte, tf.size), 0o644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+		if err := os.Chtimes(path, base, base.Add(-tf.age)); err != nil {
+			t.Fatalf("Failed to set times: %v", err)
+		}
+	}
+	return tempDir
+}
+
+// Test every sort mode, on its own and reversed with -r
+func TestRetrieveEntries_SortModes(t *testing.T) {
+	tempDir := makeSortFixture(t)
+
+	testCases := []struct {
+		args   []string
+		expect string
+	}{
+		{[]string{}, "[a.go b.txt c.txt file10 file2 README]"},
+		{[]string{"-r"}, "[README file2 file10 c.txt b.txt a.go]"},
+		{[]string{"-S"}, "[c.txt b.txt file10 file2 README a.go]"},
+		{[]string{"-Sr"}, "[a.go README file2 file10 b.txt c.txt]"},
+		{[]string{"-t"}, "[b.txt a.go c.txt README file10 file2]"},
+		{[]string{"-tr"}, "[file2 file10 README c.txt a.go b.txt]"},
+		{[]string{"-X"}, "[file10 file2 README a.go b.txt c.txt]"},
+		{[]string{"-Xr"}, "[c.txt b.txt a.go README file2 file10]"},
+		{[]string{"--sort=version"}, "[README a.go b.txt c.txt file2 file10]"},
+		{[]string{"-r", "--sort=version"}, "[file10 file2 c.txt b.txt a.go README]"},
+		{[]string{"--sort=size"}, "[c.txt b.txt file10 file2 README a.go]"},
+		{[]string{"--sort=time"}, "[b.txt a.go c.txt README file10 file2]"},
+		{[]string{"--sort=extension"}, "[file10 file2 README a.go b.txt c.txt]"},
+		{[]string{"-S", "--sort=name"}, "[a.go b.txt c.txt file10 file2 README]"},
+		{[]string{"-t", "-S"}, "[c.txt b.txt file10 file2 README a.go]"},
+	}
+
+	for _, tc := range testCases {
+		opts, _, err := internal.ParseArgs(tc.args)
+		if err != nil {
+			t.Fatalf("ParseArgs(%q) failed: %v", tc.args, err)
+		}
+
+		result := fmt.Sprint(entryNames(retrieveEntries(t, tempDir, opts)))
+		if result != tc.expect {
+			t.Errorf("RetrieveEntries(%q) = %v; want %v", tc.args, result, tc.expect)
+		}
+	}
+}
+
+// Test --sort=none keeps directory order, which -r does not change, and a later sort flag wins
+func TestRetrieveEntries_SortNone(t *testing.T) {
+	tempDir := makeSortFixture(t)
+
+	unsorted, _, _ := internal.ParseArgs([]string{"--sort=none"})
+	expect := fmt.Sprint(entryNames(retrieveEntries(t, tempDir, unsorted)))
+
+	for _, args := range [][]string{{"-U"}, {"-U", "-r"}, {"-t", "-U"}} {
+		opts, _, _ := internal.ParseArgs(args)
+		if result := fmt.Sprint(entryNames(retrieveEntries(t, tempDir, opts))); result != expect {
+			t.Errorf("RetrieveEntries(%q) = %v; want %v", args, result, expect)
+		}
+	}
+
+	opts, _, _ := internal.ParseArgs([]string{"-U", "-t"})
+	if opts.Unsorted || opts.Sort != "time" {
+		t.Errorf("Expected -t after -U to sort by time, Got %+v", opts)
+	}
+}
+
+// Test operands are ordered by the same sort as directory entries
+func TestSplitOperands_SortModes(t *testing.T) {
+	tempDir := makeSortFixture(t)
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, name := range []string{"older", "newer"} {
+		path := filepath.Join(tempDir, name)
+		if err := os.Mkdir(path, 0o755); err != nil {
+			t.Fatalf("Failed to create test directory: %v", err)
+		}
+		if err := os.Chtimes(path, base, base.Add(time.Duration(i)*time.Hour)); err != nil {
+			t.Fatalf("Failed to set times: %v", err)
+		}
+	}
+
+	file := func(name string) string { return filepath.Join(tempDir, name) }
+
+	files, dirs, _ := internal.SplitOperands([]string{file("a.go"), file("newer"), file("c.txt"), file("older")}, internal.Options{Sort: "size", Reverse: true})
+	if result := fmt.Sprint(entryNames(files)); result != fmt.Sprint([]string{file("a.go"), file("c.txt")}) {
+		t.Errorf("Expected smallest file first, Got %v", result)
+	}
+
+	_, dirs, _ = internal.SplitOperands([]string{file("older"), file("newer")}, internal.Options{Sort: "time"})
+	if fmt.Sprint(dirs) != fmt.Sprint([]string{file("newer"), file("older")}) {
+		t.Errorf("Expected newest directory first, Got %v", dirs)
+	}
+
+	_, dirs, _ = internal.SplitOperands([]string{file("older"), file("newer")}, internal.Options{Sort: "time", Reverse: true})
+	if fmt.Sprint(dirs) != fmt.Sprint([]string{file("older"), file("newer")}) {
+		t.Errorf("Expected oldest directory first with -r, Got %v", dirs)
+	}
+}
+
+// Test version order compares runs of digits by value
+func TestVersionLess(t *testing.T) {
+	testCases := []struct {
+		a, b   string
+		expect bool
+	}{
+		{"file2", "file10", true},
+		{"file10", "file2", false},
+		{"v1.9.0", "v1.10.0", true},
+		{"a02", "a2", false},
+		{"a", "a1", true},
+		{"B", "a", true},
+	}
+
+	for _, tc := range testCases {
+		if result := internal.VersionLess(tc.a, tc.b); result != tc.expect {
+			t.Errorf("VersionLess(%q, %q) = %v; want %v", tc.a, tc.b, result, tc.expect)
+		}
+	}
+}
+
+// Test --sort only accepts known words
+func TestParseArgs_InvalidSort(t *testing.T) {
+	if _, _, err := internal.ParseArgs([]string{"--sort=random"}); err == nil {
+		t.Errorf("Expected error for --sort=random, Got nil")
+	}
+}
+
+// Test --group-directories-first puts directories, and links to them, ahead of files
+// under every sort mode, each group keeping the order that sort gives it
+func TestRetrieveEntries_GroupDirectoriesFirst(t *testing.T) {
+	tempDir := makeSortFixture(t)
+	for _, name := range []string{"d1", "zdir"} {
+		if err := os.Mkdir(filepath.Join(tempDir, name), 0o755); err != nil {
+			t.Fatalf("Failed to create test directory: %v", err)
+		}
+	}
+	if err := os.Symlink("zdir", filepath.Join(tempDir, "alink")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+	if err := os.Symlink("a.go", filepath.Join(tempDir, "flink")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+	dirs := map[string]bool{".": true, "..": true, "alink": true, "d1": true, "zdir": true}
+
+	opts, _, _ := internal.ParseArgs([]string{"--group-directories-first"})
+	result := fmt.Sprint(entryNames(retrieveEntries(t, tempDir, opts)))
+	if expect := "[alink d1 zdir a.go b.txt c.txt file10 file2 flink README]"; result != expect {
+		t.Errorf("Expected %v, Got %v", expect, result)
+	}
+
+	for _, args := range [][]string{{}, {"-r"}, {"-S"}, {"-Sr"}, {"-t"}, {"-tr"}, {"-X"}, {"-Xr"}, {"-v"}, {"-vr"}, {"-a"}, {"-ar"}} {
+		opts, _, _ := internal.ParseArgs(args)
+		var expect []string
+		sorted := entryNames(retrieveEntries(t, tempDir, opts))
+		for _, group := range []bool{true, false} {
+			for _, name := range sorted {
+				if dirs[name] == group {
+					expect = append(expect, name)
+				}
+			}
+		}
+
+		opts.GroupDirectoriesFirst = true
+		if result := entryNames(retrieveEntries(t, tempDir, opts)); fmt.Sprint(result) != fmt.Sprint(expect) {
+			t.Errorf("RetrieveEntries(%q, grouped) = %v; want %v", args, result, expect)
+		}
+	}
+
+	// Unsorted listings are never grouped, as in ls
+	unsorted, _, _ := internal.ParseArgs([]string{"-U"})
+	expect := fmt.Sprint(entryNames(retrieveEntries(t, tempDir, unsorted)))
+	unsorted.GroupDirectoriesFirst = true
+	if result := fmt.Sprint(entryNames(retrieveEntries(t, tempDir, unsorted))); result != expect {
+		t.Errorf("Expected -U to ignore grouping: %v, Got %v", expect, result)
+	}
+}
+
+// Test a directory sorts by name alone, before names that extend it
+func TestRetrieveEntries_DirectoryIndex(t *testing.T) {
+	tempDir := t.TempDir()
+	for _, name := range []string{"sub.md", "sub-notes", "sub0"} {
+		if err := os.WriteFile(filepath.Join(tempDir, name), nil, 0o644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(tempDir, "sub"), 0o755); err != nil {
+		t.Fatalf("Failed to create test directory: %v", err)
+	}
+
+	result := fmt.Sprint(entryNames(retrieveEntries(t, tempDir, internal.Options{})))
+	if expect := "[sub sub-notes sub.md sub0]"; result != expect {
+		t.Errorf("Expected %v, Got %v", expect, result)
+	}
+}
+
+// Test names compare as their lowercased forms do, byte for byte, as the default sort expects
+func TestNameLess(t *testing.T) {
+	names := []string{"a", "A", "b", "B.txt", "sub", "sub.md", "Ägypten", "zebra", "ÉCOLE", "école", "", "\xff", "a\xffb"}
+
+	for _, a := range names {
+		for _, b := range names {
+			expect := strings.ToLower(a) < strings.ToLower(b)
+			if result := internal.NameLess(a, b); result != expect {
+				t.Errorf("NameLess(%q, %q) = %v; want %v", a, b, result, expect)
+			}
+		}
+	}
+}
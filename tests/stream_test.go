@@ -0,0 +1,114 @@
+package tests
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	internal "github.com/DavJesse/ls-clone/internal/ls"
+)
+
+// Creates a directory holding 'count' empty files
+func makeLargeDir(tb testing.TB, count int) string {
+	dir := tb.TempDir()
+	for i := 0; i < count; i++ {
+		if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("f%06d", i)), nil, 0o644); err != nil {
+			tb.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+	return dir
+}
+
+// Test every entry is streamed across batches, in directory order
+func TestStreamDirectory_Batches(t *testing.T) {
+	count := internal.BatchSize*2 + 5
+	dir := makeLargeDir(t, count)
+
+	var streamed []string
+	err := internal.StreamDirectory(dir, internal.Options{}, func(file internal.FileInfo) error {
+		streamed = append(streamed, file.Name)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("StreamDirectory failed: %v", err)
+	}
+
+	if len(streamed) != count {
+		t.Fatalf("Expected %d entries, Got %d", count, len(streamed))
+	}
+
+	// An unsorted listing reads the same directory order
+	unsorted := entryNames(retrieveEntries(t, dir, internal.Options{Unsorted: true}))
+	if strings.Join(unsorted, " ") != strings.Join(streamed, " ") {
+		t.Errorf("Expected unsorted listing to keep directory order")
+	}
+}
+
+// Test an emit error stops the stream and is returned
+func TestStreamDirectory_StopsOnError(t *testing.T) {
+	dir := makeLargeDir(t, 10)
+	stop := errors.New("stop")
+
+	var calls int
+	err := internal.StreamDirectory(dir, internal.Options{}, func(file internal.FileInfo) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) || calls != 1 {
+		t.Errorf("Expected one call and the emit error, Got %d calls and %v", calls, err)
+	}
+}
+
+// Test streamed short listings print one entry per line, '.' and '..' first with -a
+func TestStreamDirectoryListing(t *testing.T) {
+	dir := makeLargeDir(t, 3)
+
+	var buf bytes.Buffer
+	opts := internal.Options{Unsorted: true, All: true}
+	if err := internal.StreamDirectoryListing(&buf, dir, opts, false); err != nil {
+		t.Fatalf("StreamDirectoryListing failed: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 5 || !strings.Contains(lines[0], ".\033") || !strings.Contains(lines[1], "..\033") {
+		t.Errorf("Unexpected listing: %q", lines)
+	}
+}
+
+// Test the legacy RetrieveFileInfo formats display strings from the compact entries
+func TestRetrieveFileInfo_Decorated(t *testing.T) {
+	dir := makeLargeDir(t, 1)
+
+	files := internal.RetrieveFileInfo(dir, false)
+	if files[0].DocName != "f000000" || files[0].DocPerm == "" || files[0].Index != "f000000" {
+		t.Errorf("Expected display strings, Got %+v", files[0])
+	}
+	if entry := retrieveEntries(t, dir, internal.Options{})[0]; files[0].Entry.Path != entry.Path || files[0].Entry.Meta != entry.Meta {
+		t.Errorf("Expected the compact entry %+v, Got %+v", entry, files[0].Entry)
+	}
+}
+
+// Compares memory use of sorted reads and streamed reads of a large directory
+func BenchmarkListDirectory_Sorted(b *testing.B) {
+	dir := makeLargeDir(b, 5000)
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		internal.ListDirectory(dir, internal.Options{})
+	}
+}
+
+func BenchmarkStreamDirectory(b *testing.B) {
+	dir := makeLargeDir(b, 5000)
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		internal.StreamDirectory(dir, internal.Options{}, func(internal.FileInfo) error { return nil })
+	}
+}
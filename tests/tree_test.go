@@ -0,0 +1,125 @@
+package tests
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	internal "github.com/DavJesse/ls-clone/internal/ls"
+)
+
+// Builds a small tree: a/b/deep.txt, a/one.txt and top.txt
+func makeTreeFixture(t *testing.T) string {
+	tempDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(tempDir, "a", "b"), 0o755); err != nil {
+		t.Fatalf("Failed to create test directories: %v", err)
+	}
+	for _, name := range []string{"a/b/deep.txt", "a/one.txt", "top.txt"} {
+		if err := os.WriteFile(filepath.Join(tempDir, name), nil, 0o644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+	return tempDir
+}
+
+// Test connectors and summary of a full tree
+func TestRenderTree_Unicode(t *testing.T) {
+	tempDir := makeTreeFixture(t)
+
+	var buf bytes.Buffer
+	files := retrieveEntries(t, tempDir, internal.Options{Recursive: true})
+	if err := internal.RenderTree(&buf, "root", files, internal.Options{}, internal.UnicodeConnectors); err != nil {
+		t.Fatalf("RenderTree failed: %v", err)
+	}
+
+	expect := "root\n" +
+		"├── \033[01;34ma\033[0m/\n" +
+		"│   ├── \033[01;34mb\033[0m/\n" +
+		"│   │   └── deep.txt\n" +
+		"│   └── one.txt\n" +
+		"└── top.txt\n" +
+		"\n2 directories, 3 files\n"
+	if buf.String() != expect {
+		t.Errorf("Expected:\n%v\nGot:\n%v", expect, buf.String())
+	}
+}
+
+// Test --max-depth stops descending and ASCII connectors are used
+func TestRenderTree_MaxDepthASCII(t *testing.T) {
+	tempDir := makeTreeFixture(t)
+
+	var buf bytes.Buffer
+	files := retrieveEntries(t, tempDir, internal.Options{Recursive: true})
+	opts := internal.Options{MaxDepth: 1}
+	if err := internal.RenderTree(&buf, "root", files, opts, internal.ASCIIConnectors); err != nil {
+		t.Fatalf("RenderTree failed: %v", err)
+	}
+
+	expect := "root\n" +
+		"|-- \033[01;34ma\033[0m/\n" +
+		"`-- top.txt\n" +
+		"\n1 directory, 1 file\n"
+	if buf.String() != expect {
+		t.Errorf("Expected:\n%v\nGot:\n%v", expect, buf.String())
+	}
+}
+
+// Test locale detection follows LC_ALL, LC_CTYPE, then LANG
+func TestIsUTF8Locale(t *testing.T) {
+	testCases := []struct {
+		env    map[string]string
+		expect bool
+	}{
+		{map[string]string{}, false},
+		{map[string]string{"LANG": "en_US.UTF-8"}, true},
+		{map[string]string{"LANG": "en_US.utf8"}, true},
+		{map[string]string{"LC_ALL": "C", "LANG": "en_US.UTF-8"}, false},
+		{map[string]string{"LC_CTYPE": "C.UTF-8", "LANG": "C"}, true},
+	}
+
+	for _, tc := range testCases {
+		getenv := func(name string) string { return tc.env[name] }
+		if result := internal.IsUTF8Locale(getenv); result != tc.expect {
+			t.Errorf("IsUTF8Locale(%v) = %v; want %v", tc.env, result, tc.expect)
+		}
+	}
+}
+
+// Test --max-depth stops the scan itself, leaving deeper directories unread
+func TestRetrieveEntries_MaxDepth(t *testing.T) {
+	tempDir := makeTreeFixture(t)
+
+	files := retrieveEntries(t, tempDir, internal.Options{Recursive: true, MaxDepth: 1})
+	if len(files) != 2 || files[0].Name != "a" || files[0].RecursiveList != nil {
+		t.Errorf("Expected 'a' left unread, Got %+v", files)
+	}
+
+	files = retrieveEntries(t, tempDir, internal.Options{Recursive: true, MaxDepth: 2})
+	if b := files[0].RecursiveList[0]; b.Name != "b" || len(files[0].RecursiveList) != 2 || b.RecursiveList != nil {
+		t.Errorf("Expected 'a' read and 'b' left unread, Got %+v", files[0].RecursiveList)
+	}
+}
+
+// Test --tree reports missing operands with status 2 and names file operands on their own
+func TestRun_TreeOperands(t *testing.T) {
+	tempDir := makeTreeFixture(t)
+	missing := filepath.Join(tempDir, "nope")
+	file := filepath.Join(tempDir, "a", "one.txt")
+
+	var stdout, stderr bytes.Buffer
+	status := internal.Run([]string{"--tree", missing, file, filepath.Join(tempDir, "a")}, mapEnv(nil), &stdout, &stderr)
+
+	if expect := "my-ls: cannot access '" + missing + "': No such file or directory\n"; status != 2 || stderr.String() != expect {
+		t.Errorf("Expected status 2 and %q, Got %d, %q", expect, status, stderr.String())
+	}
+	expect := file + "\n" +
+		filepath.Join(tempDir, "a") + "\n" +
+		"|-- \033[01;34mb\033[0m/\n" +
+		"|   `-- deep.txt\n" +
+		"`-- one.txt\n" +
+		"\n1 directory, 2 files\n"
+	if stdout.String() != expect {
+		t.Errorf("Expected:\n%v\nGot:\n%v", expect, stdout.String())
+	}
+}